@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/cyclone-github/jotti/pkg/jotti"
+)
+
+// files waiting for a combined -batch upload
+type uploadBatch struct {
+	max     int // files per request
+	results []scanResult
+	bytes   int64
+}
+
+func (b *uploadBatch) add(r scanResult) {
+	b.results = append(b.results, r)
+	b.bytes += r.size
+}
+
+func (b *uploadBatch) full() bool {
+	return len(b.results) >= b.max
+}
+
+// keep a single request within Jotti's upload limit
+func (b *uploadBatch) wouldOverflow(size int64) bool {
+	return len(b.results) > 0 && b.bytes+size > jotti.MaxUploadSize
+}
+
+// empty the batch, returning the files queued so far
+func (b *uploadBatch) take() []scanResult {
+	results := b.results
+	b.results, b.bytes = nil, 0
+	return results
+}
+
+// upload the queued files left at the end of a run
+func (b *uploadBatch) flush(ctx context.Context, opt *scanOptions, w io.Writer) []scanResult {
+	return uploadQueued(ctx, b.take(), opt, w)
+}
+
+// upload queued files in one request and return their final results
+func uploadQueued(ctx context.Context, results []scanResult, opt *scanOptions, w io.Writer) []scanResult {
+	if len(results) == 0 {
+		return nil
+	}
+
+	paths := make([]string, len(results))
+	for i, r := range results {
+		paths[i] = r.diskPath()
+	}
+
+	fmt.Fprintf(w, "Uploading batch of %d files: ", len(paths))
+	start := time.Now()
+	jobURL, err := client.UploadBatch(dumpAs(ctx, batchDumpName(results)), paths)
+	// share the request's duration between its files
+	share := time.Since(start) / time.Duration(len(results))
+	for i := range results {
+		results[i].timings.upload += share
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		} else {
+			slog.Error("Error uploading batch", "files", len(results), "error", err)
+		}
+		for i := range results {
+			results[i].queued = false
+			results[i].setError(err)
+		}
+		return results
+	}
+	fmt.Fprintln(w, "OK")
+	// the job page covers the whole batch, each file keeps its own hash URL
+	if jobURL != "" {
+		fmt.Fprintln(w, jobURL)
+	}
+
+	for i := range results {
+		results[i].queued = false
+		completeUpload(ctx, &results[i], opt, w)
+	}
+	return results
+}
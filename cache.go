@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/cyclone-github/jotti/pkg/jotti"
+)
+
+// cached Jotti result for a single hash
+type cacheEntry struct {
+	Found     bool                 `json:"found"`
+	Uploaded  bool                 `json:"uploaded,omitempty"`
+	URL       string               `json:"url,omitempty"`
+	Permalink string               `json:"permalink,omitempty"`
+	ScanDate  *time.Time           `json:"scan_date,omitempty"` // last scan of a found hash
+	Detected  int                  `json:"detected"`
+	Engines   []jotti.EngineResult `json:"engines,omitempty"`
+	Checked   time.Time            `json:"checked"`
+	TTL       time.Duration        `json:"ttl,omitempty"` // set on put, 0 in caches written before per-entry TTLs
+}
+
+// a hash Jotti didn't know, it may be uploaded by someone else any time
+func (e cacheEntry) negative() bool {
+	return !e.Found && !e.Uploaded
+}
+
+// on-disk cache of seen hashes keyed by SHA1, a nil cache is disabled
+type hashCache struct {
+	mu          sync.Mutex
+	path        string
+	ttl         time.Duration
+	negativeTTL time.Duration // for hashes not on Jotti
+	entries     map[string]cacheEntry
+	dirty       bool
+}
+
+// ~/.cache/jotti/seen.json on Linux
+func defaultCachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "jotti", "seen.json"), nil
+}
+
+// load cache from path, a missing file yields an empty cache
+func loadCache(path string, ttl, negativeTTL time.Duration) (*hashCache, error) {
+	c := &hashCache{
+		path:        path,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		entries:     make(map[string]cacheEntry),
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &c.entries); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// look up a hash seen within the TTL
+func (c *hashCache) get(sha1 string) (cacheEntry, bool) {
+	if c == nil {
+		return cacheEntry{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[sha1]
+	if !ok || time.Since(e.Checked) > c.entryTTL(e) {
+		return cacheEntry{}, false
+	}
+	return e, true
+}
+
+// the stored TTL, shortened if the flags have been lowered since
+func (c *hashCache) entryTTL(e cacheEntry) time.Duration {
+	ttl := c.ttl
+	if e.negative() {
+		ttl = c.negativeTTL
+	}
+	if e.TTL > 0 {
+		ttl = min(ttl, e.TTL)
+	}
+	return ttl
+}
+
+func (c *hashCache) put(sha1 string, e cacheEntry) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e.Checked = time.Now()
+	e.TTL = c.ttl
+	if e.negative() {
+		e.TTL = c.negativeTTL
+	}
+	c.entries[sha1] = e
+	c.dirty = true
+}
+
+// write cache atomically so an interrupted run can't corrupt it
+func (c *hashCache) save() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return nil
+	}
+
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), ".seen-*.json")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	c.dirty = false
+	return nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCacheTTLs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seen.json")
+	c, err := loadCache(path, 24*time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.put("found", cacheEntry{Found: true})
+	c.put("uploaded", cacheEntry{Uploaded: true})
+	c.put("missing", cacheEntry{})
+
+	// age every entry by two hours, past the negative TTL only
+	for k, e := range c.entries {
+		e.Checked = e.Checked.Add(-2 * time.Hour)
+		c.entries[k] = e
+	}
+	for sha1, want := range map[string]bool{"found": true, "uploaded": true, "missing": false} {
+		if _, ok := c.get(sha1); ok != want {
+			t.Errorf("get(%q) after 2h = %v, want %v", sha1, ok, want)
+		}
+	}
+}
+
+func TestCacheEntryTTL(t *testing.T) {
+	c := &hashCache{ttl: 24 * time.Hour, negativeTTL: time.Hour}
+	tests := []struct {
+		e    cacheEntry
+		want time.Duration
+	}{
+		{cacheEntry{Found: true}, 24 * time.Hour},
+		{cacheEntry{}, time.Hour},
+		// written with a longer TTL, the lowered flag wins
+		{cacheEntry{Found: true, TTL: 48 * time.Hour}, 24 * time.Hour},
+		// written with a shorter TTL, it is kept
+		{cacheEntry{Found: true, TTL: 10 * time.Minute}, 10 * time.Minute},
+		{cacheEntry{TTL: 30 * time.Minute}, 30 * time.Minute},
+	}
+	for i, tt := range tests {
+		if got := c.entryTTL(tt.e); got != tt.want {
+			t.Errorf("case %d: entryTTL = %s, want %s", i, got, tt.want)
+		}
+	}
+}
+
+func TestCacheSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jotti", "seen.json")
+	c, err := loadCache(path, time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.put("abc", cacheEntry{Found: true, URL: "https://jotti.example/abc", Detected: 2})
+	if err := c.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err = loadCache(path, time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, ok := c.get("abc")
+	if !ok || e.URL != "https://jotti.example/abc" || e.Detected != 2 || e.TTL != time.Hour {
+		t.Errorf("reloaded entry = %+v, %v", e, ok)
+	}
+}
+
+func TestCacheNil(t *testing.T) {
+	var c *hashCache
+	c.put("abc", cacheEntry{Found: true})
+	if _, ok := c.get("abc"); ok {
+		t.Error("disabled cache returned an entry")
+	}
+	if err := c.save(); err != nil {
+		t.Error(err)
+	}
+}
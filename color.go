@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// ANSI colors for verdicts, only used when colorOutput is set
+const (
+	ansiRed    = "\033[31m"
+	ansiGreen  = "\033[32m"
+	ansiYellow = "\033[33m"
+	ansiReset  = "\033[0m"
+	ansiPlain  = "\033[39m" // default color, same length as the others for table padding
+)
+
+var (
+	// set at startup when status output goes to a terminal and color isn't disabled
+	colorOutput bool
+	// -no-color flag
+	noColor bool
+)
+
+// single place deciding whether ANSI output is allowed on f: not with -no-color,
+// a non-empty NO_COLOR (https://no-color.org), TERM=dumb, or when f isn't a terminal
+func colorEnabled(f *os.File) bool {
+	if noColor || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return isTerminal(f)
+}
+
+func colorize(color, s string) string {
+	if !colorOutput {
+		return s
+	}
+	return color + s + ansiReset
+}
+
+// CLEAN, PENDING or DETECTED label with the detection ratio, e.g. "DETECTED 3/25"
+func verdictLabel(detected, total int, pending bool) string {
+	ratio := fmt.Sprintf("%d/%d", detected, total)
+	switch {
+	case detected > 0:
+		return colorize(ansiRed, "DETECTED "+ratio)
+	case pending:
+		return colorize(ansiYellow, "PENDING "+ratio)
+	default:
+		return colorize(ansiGreen, "CLEAN "+ratio)
+	}
+}
+
+// summary STATUS column, every value including the header carries equally long
+// escape codes so tabwriter padding stays aligned
+func statusLabel(status string, color bool) string {
+	if !color {
+		return status
+	}
+	switch status {
+	case "STATUS":
+		return ansiPlain + status + ansiReset
+	case "ERROR":
+		return ansiRed + status + ansiReset
+	case "FOUND", "UPLOADED":
+		return ansiGreen + status + ansiReset
+	default:
+		return ansiYellow + status + ansiReset
+	}
+}
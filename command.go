@@ -0,0 +1,32 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+)
+
+// flags of the combined scan which don't apply to a subcommand
+var commandSkips = map[string][]string{
+	// search never uploads
+	"search": {"search-only", "force-upload", "hash-only", "batch", "confirm", "form-field", "upload-url",
+		"rate-limit", "delay", "jitter", "verify-upload", "wait-results", "wait-timeout"},
+	// upload never searches first, so only uploaded results are reported
+	"upload": {"search-only", "force-upload", "hash-only", "hashes", "max-age", "permalink", "vt-key", "negative-cache-ttl"},
+}
+
+// FlagSet of command holding the flag.CommandLine flags that apply to it, values are shared
+// so the variables flag.CommandLine defined are set by parsing it
+func commandFlags(command, name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	flag.VisitAll(func(f *flag.Flag) {
+		if !slices.Contains(commandSkips[command], f.Name) {
+			fs.Var(f.Value, f.Name, f.Usage)
+		}
+	})
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of %s:\n", name)
+		fs.PrintDefaults()
+	}
+	return fs
+}
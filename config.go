@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// ~/.config/jotti/config.json on Linux
+func defaultConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "jotti", "config.json"), nil
+}
+
+// short and long names bound to the same variable, keyed by the short one
+var flagAliases = map[string]string{"r": "recursive", "v": "verbose"}
+
+// long name of an alias, other names are returned as is
+func canonicalFlag(name string) string {
+	if long, ok := flagAliases[name]; ok {
+		return long
+	}
+	return name
+}
+
+// apply flag defaults from a JSON object keyed by flag name, e.g. {"proxy": "socks5://127.0.0.1:9050", "concurrency": 2},
+// flags given on the command line win and a missing file is only an error when required
+func loadConfig(fs *flag.FlagSet, path string, required bool) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !required {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var values map[string]any
+	if err := json.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	// typing -r also counts for a "recursive" key and the other way round
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[canonicalFlag(f.Name)] = true })
+	for name, v := range values {
+		if fs.Lookup(name) == nil {
+			// a shared config may set flags of other commands, e.g. "batch" for search
+			if flag.Lookup(name) != nil {
+				continue
+			}
+			return fmt.Errorf("%s: unknown flag %q", path, name)
+		}
+		if explicit[canonicalFlag(name)] {
+			continue
+		}
+		// repeatable flags such as -header take a list, each entry is set in turn
+		if list, ok := v.([]any); ok {
+			for _, item := range list {
+				if err := fs.Set(name, fmt.Sprint(item)); err != nil {
+					return fmt.Errorf("%s: invalid value for %q: %v", path, name, err)
+				}
+			}
+			continue
+		}
+		value := fmt.Sprint(v)
+		if n, ok := v.(float64); ok {
+			// JSON numbers decode as float64, keep integers free of exponents
+			value = strconv.FormatFloat(n, 'f', -1, 64)
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("%s: invalid value for %q: %v", path, name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCanonicalFlag(t *testing.T) {
+	for name, want := range map[string]string{"r": "recursive", "v": "verbose", "recursive": "recursive", "proxy": "proxy"} {
+		if got := canonicalFlag(name); got != want {
+			t.Errorf("canonicalFlag(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestLoadConfigAliasOnCommandLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"recursive": false, "concurrency": 4}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fs := flag.NewFlagSet("jotti", flag.ContinueOnError)
+	var recursive bool
+	fs.BoolVar(&recursive, "recursive", false, "")
+	fs.BoolVar(&recursive, "r", false, "")
+	concurrency := fs.Int("concurrency", 1, "")
+	if err := fs.Parse([]string{"-r"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadConfig(fs, path, true); err != nil {
+		t.Fatal(err)
+	}
+	if !recursive {
+		t.Error("config recursive=false overrode -r")
+	}
+	if *concurrency != 4 {
+		t.Errorf("concurrency = %d, want 4 from the config", *concurrency)
+	}
+}
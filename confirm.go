@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// asks before each upload for -confirm, answers are read from stdin
+type confirmer struct {
+	mu          sync.Mutex // one prompt at a time with -concurrency
+	in          *bufio.Reader
+	prompt      io.Writer
+	interactive bool // false when stdin isn't a terminal, every upload is declined
+}
+
+func newConfirmer() *confirmer {
+	return &confirmer{
+		in:          bufio.NewReader(os.Stdin),
+		prompt:      stderr,
+		interactive: isTerminal(os.Stdin),
+	}
+}
+
+// report whether the user agreed to upload filePath, anything but y or yes declines
+func (c *confirmer) ask(filePath string) bool {
+	if !c.interactive {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fmt.Fprintf(c.prompt, "Upload %s to Jotti? [y/N] ", filePath)
+	line, err := c.in.ReadString('\n')
+	if err != nil && line == "" {
+		fmt.Fprintln(c.prompt)
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
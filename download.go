@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/cyclone-github/jotti/pkg/jotti"
+)
+
+// true for http:// and https:// arguments, which are downloaded instead of read from disk
+func isURL(arg string) bool {
+	lower := strings.ToLower(arg)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// redirects may not leave http(s), where they may connect to is checked by guardDownloads
+func checkDownloadRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return fmt.Errorf("refusing redirect to %s", req.URL)
+	}
+	return nil
+}
+
+// host of the URL argument a download was started from, set on its request context
+type downloadHostKey struct{}
+
+// dial through dialer, but refuse connections a download makes to this machine or the local
+// network unless they go to the host of the URL argument itself, or to the proxy for it.
+// The check runs on the resolved address, so hostnames pointing there are caught too
+func guardDownloads(transport *http.Transport, dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	guarded := *dialer
+	guarded.Control = refuseLocal
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		trusted, ok := ctx.Value(downloadHostKey{}).(string)
+		host, _, _ := net.SplitHostPort(addr)
+		if !ok || strings.EqualFold(host, trusted) || isProxyFor(transport, host, trusted) {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		return guarded.DialContext(ctx, network, addr)
+	}
+}
+
+// true if host is the proxy transport sends http or https requests for target through
+func isProxyFor(transport *http.Transport, host, target string) bool {
+	if transport.Proxy == nil {
+		return false
+	}
+	for _, scheme := range []string{"http", "https"} {
+		proxyURL, err := transport.Proxy(&http.Request{URL: &url.URL{Scheme: scheme, Host: target}})
+		if err == nil && proxyURL != nil && strings.EqualFold(proxyURL.Hostname(), host) {
+			return true
+		}
+	}
+	return false
+}
+
+// net.Dialer.Control refusing loopback, private and link-local addresses
+func refuseLocal(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip != nil && (ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified()) {
+		return fmt.Errorf("refusing connection to local address %s", host)
+	}
+	return nil
+}
+
+// download rawURL into the scratch directory, returns the path of the saved file
+func (s *scratchDir) download(ctx context.Context, rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	// the URL argument may point at the local network, only where it redirects to is checked
+	ctx = context.WithValue(ctx, downloadHostKey{}, u.Hostname())
+	request, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if client.UserAgent != "" {
+		request.Header.Set("User-Agent", client.UserAgent)
+	}
+
+	httpClient := *client.HTTPClient
+	httpClient.CheckRedirect = checkDownloadRedirect
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download failed: %s", response.Status)
+	}
+	if response.ContentLength > jotti.MaxUploadSize {
+		return "", &jotti.FileTooLargeError{Size: response.ContentLength, Max: jotti.MaxUploadSize}
+	}
+
+	// keep the remote name so the report still says what was scanned
+	name := path.Base(response.Request.URL.Path)
+	if name == "/" || name == "." || !filepath.IsLocal(name) {
+		name = "download"
+	}
+	dir, err := s.mkdir(response.Request.URL.Hostname())
+	if err != nil {
+		return "", err
+	}
+	target := filepath.Join(dir, name)
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return "", err
+	}
+	// the server may not send a length, or lie about it
+	n, err := io.Copy(out, io.LimitReader(response.Body, jotti.MaxUploadSize+1))
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && n > jotti.MaxUploadSize {
+		err = fmt.Errorf("%w: download exceeds %d byte limit", jotti.ErrFileTooLarge, jotti.MaxUploadSize)
+	}
+	if err != nil {
+		os.Remove(target)
+		return "", err
+	}
+	return target, nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// route downloads through a transport guarded like the one main builds
+func guardedClient(t *testing.T) {
+	t.Helper()
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	configureConnectTimeout(transport, time.Second)
+	old := client.HTTPClient.Transport
+	client.HTTPClient.Transport = transport
+	t.Cleanup(func() {
+		client.HTTPClient.Transport = old
+		transport.CloseIdleConnections()
+	})
+}
+
+// server redirecting /start to location and serving the sample at /sample.bin
+func redirectServer(t *testing.T, location func(srv *httptest.Server) string) *httptest.Server {
+	t.Helper()
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/start" {
+			http.Redirect(w, r, location(srv), http.StatusFound)
+			return
+		}
+		w.Write([]byte("sample"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestDownloadRefusesLocalRedirects(t *testing.T) {
+	guardedClient(t)
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// redirect targets on the server's port under another name
+	tests := []struct{ name, host string }{
+		{"ip", "127.0.0.2"},
+		{"localhost", "localhost"},
+		{"hostname", hostname},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.name == "hostname" {
+				addrs, err := net.LookupIP(hostname)
+				if err != nil || len(addrs) == 0 || refuseLocal("tcp", net.JoinHostPort(addrs[0].String(), "80"), nil) == nil {
+					t.Skipf("%s doesn't resolve to a local address", hostname)
+				}
+			}
+			srv := redirectServer(t, func(srv *httptest.Server) string {
+				_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+				return "http://" + net.JoinHostPort(tt.host, port) + "/sample.bin"
+			})
+			scratch := &scratchDir{}
+			defer scratch.cleanup()
+			_, err := scratch.download(context.Background(), srv.URL+"/start")
+			if err == nil || !strings.Contains(err.Error(), "refusing connection to local address") {
+				t.Errorf("download err = %v, want a refused local address", err)
+			}
+		})
+	}
+}
+
+func TestDownloadFollowsSameHostRedirect(t *testing.T) {
+	guardedClient(t)
+	// the URL argument itself may be local, so may a redirect back to it
+	srv := redirectServer(t, func(srv *httptest.Server) string { return srv.URL + "/sample.bin" })
+	scratch := &scratchDir{}
+	defer scratch.cleanup()
+	target, err := scratch.download(context.Background(), srv.URL+"/start")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(target); err != nil || string(data) != "sample" {
+		t.Errorf("downloaded %q, %v", data, err)
+	}
+}
+
+func TestRefuseLocal(t *testing.T) {
+	for _, addr := range []string{"127.0.0.1:80", "[::1]:443", "10.1.2.3:80", "192.168.0.1:80", "169.254.169.254:80", "0.0.0.0:80"} {
+		if refuseLocal("tcp", addr, nil) == nil {
+			t.Errorf("%s was allowed", addr)
+		}
+	}
+	for _, addr := range []string{"93.184.216.34:80", "[2606:4700::1111]:443"} {
+		if err := refuseLocal("tcp", addr, nil); err != nil {
+			t.Errorf("%s refused: %v", addr, err)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// directory for -dump-dir response bodies, empty disables dumping
+var dumpDir string
+
+type dumpNameKey struct{}
+
+// name the response bodies of requests made with ctx, e.g. "<sha1>-search"
+func dumpAs(ctx context.Context, name string) context.Context {
+	if dumpDir == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, dumpNameKey{}, name)
+}
+
+// writes each response body of a named request to dumpDir/<name>.html
+type dumpTransport struct {
+	transport http.RoundTripper
+}
+
+func (t *dumpTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.transport.RoundTrip(req)
+	name, ok := req.Context().Value(dumpNameKey{}).(string)
+	if err != nil || !ok {
+		return resp, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dumpDir, name+".html")
+	if err := os.WriteFile(path, body, 0o644); err != nil {
+		slog.Error("Error writing -dump-dir file", "file", path, "error", err)
+	}
+	return resp, nil
+}
+
+// batch uploads are named after their first file
+func batchDumpName(results []scanResult) string {
+	return fmt.Sprintf("%s-batch-upload", results[0].SHA1)
+}
@@ -0,0 +1,74 @@
+package main
+
+// -fail-on conditions, empty keeps the default exit codes
+var failOnModes = []string{"found", "notfound", "detected", "none"}
+
+func validFailOn(mode string) bool {
+	if mode == "" {
+		return true
+	}
+	for _, m := range failOnModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// exit code for a single file under -fail-on, searchOnly for -search-only and the search command
+func fileExitCode(r scanResult, failOn string, searchOnly bool) int {
+	detected := r.Detected > 0 || (r.VirusTotal != nil && r.VirusTotal.Malicious > 0)
+	switch failOn {
+	case "found":
+		if r.Found {
+			return exitFound
+		}
+	case "notfound":
+		// searched and unknown to Jotti, whether or not it was uploaded afterwards
+		if r.Skipped || r.Uploaded {
+			return exitNotFound
+		}
+	case "detected":
+		if detected {
+			return exitDetected
+		}
+	case "none":
+	default:
+		switch {
+		case detected:
+			return exitDetected
+		case r.Error != nil:
+			return exitError
+		// a declined upload or a bare hash in a plain scan is not a failure
+		case r.Skipped && searchOnly:
+			return exitNotFound
+		}
+	}
+	if r.Error != nil {
+		return exitError
+	}
+	return exitClean
+}
+
+// combine exit codes of a multi-file run, the worst case wins: the -fail-on
+// condition (or a detection by default) beats errors, which beat not-found
+func worseExit(a, b int, failOn string) int {
+	rank := func(code int) int {
+		switch code {
+		case exitDetected, exitFound:
+			return 3
+		case exitNotFound:
+			if failOn == "notfound" {
+				return 3
+			}
+			return 1
+		case exitError:
+			return 2
+		}
+		return 0
+	}
+	if rank(b) > rank(a) {
+		return b
+	}
+	return a
+}
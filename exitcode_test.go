@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFileExitCode(t *testing.T) {
+	failed := scanResult{}
+	failed.setError(errors.New("boom"))
+	tests := []struct {
+		name       string
+		r          scanResult
+		failOn     string
+		searchOnly bool
+		want       int
+	}{
+		{"clean", scanResult{Found: true}, "", false, exitClean},
+		{"detected", scanResult{Found: true, Detected: 1}, "", false, exitDetected},
+		{"error", failed, "", false, exitError},
+		{"not found with -search-only", scanResult{Skipped: true}, "", true, exitNotFound},
+		{"declined upload", scanResult{Skipped: true, SkipReason: "upload declined"}, "", false, exitClean},
+		{"bare hash not found", scanResult{Skipped: true}, "", false, exitClean},
+		{"uploaded", scanResult{Uploaded: true}, "", false, exitClean},
+		{"-fail-on found", scanResult{Found: true}, "found", false, exitFound},
+		{"-fail-on notfound", scanResult{Uploaded: true}, "notfound", false, exitNotFound},
+		{"-fail-on none", scanResult{Found: true, Detected: 3}, "none", false, exitClean},
+		{"-fail-on found keeps errors", failed, "found", false, exitError},
+	}
+	for _, tt := range tests {
+		if got := fileExitCode(tt.r, tt.failOn, tt.searchOnly); got != tt.want {
+			t.Errorf("%s: fileExitCode = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWorseExit(t *testing.T) {
+	tests := []struct {
+		a, b   int
+		failOn string
+		want   int
+	}{
+		{exitClean, exitNotFound, "", exitNotFound},
+		{exitNotFound, exitError, "", exitError},
+		{exitError, exitDetected, "", exitDetected},
+		{exitDetected, exitError, "", exitDetected},
+		{exitError, exitNotFound, "notfound", exitNotFound},
+		{exitFound, exitError, "found", exitFound},
+	}
+	for _, tt := range tests {
+		if got := worseExit(tt.a, tt.b, tt.failOn); got != tt.want {
+			t.Errorf("worseExit(%d, %d, %q) = %d, want %d", tt.a, tt.b, tt.failOn, got, tt.want)
+		}
+	}
+}
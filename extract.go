@@ -0,0 +1,106 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cyclone-github/jotti/pkg/jotti"
+)
+
+// true if filePath should be unpacked rather than scanned as is
+func isZip(filePath string) bool {
+	return strings.EqualFold(filepath.Ext(filePath), ".zip")
+}
+
+// caps on what -extract writes per archive, so a zip bomb can't fill the temp directory
+var (
+	maxExtractFiles       = 10000
+	maxExtractSize  int64 = 4 << 30
+)
+
+// extract the regular files of archive one at a time, each named archive.zip:entry for output,
+// handing each to fn until it returns false, and report how many were extracted
+func (s *scratchDir) extract(archive string, fn func(fileInput) bool) (int, error) {
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	// one directory per archive so entries with the same name don't collide
+	dest, err := s.mkdir(filepath.Base(archive))
+	if err != nil {
+		return 0, err
+	}
+
+	count, total := 0, int64(0)
+	for _, f := range r.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		// zip-slip, never write outside dest
+		name := filepath.FromSlash(f.Name)
+		if !filepath.IsLocal(name) {
+			slog.Warn("Skipping unsafe path", "file", f.Name, "archive", archive)
+			continue
+		}
+		if f.UncompressedSize64 > uint64(jotti.MaxUploadSize) {
+			err := &jotti.FileTooLargeError{Size: int64(f.UncompressedSize64), Max: jotti.MaxUploadSize}
+			slog.Warn("Skipping", "file", f.Name, "archive", archive, "error", err)
+			continue
+		}
+		if count == maxExtractFiles {
+			slog.Warn("Stopping extraction, too many entries", "archive", archive, "max_files", maxExtractFiles)
+			break
+		}
+		if total+int64(f.UncompressedSize64) > maxExtractSize {
+			slog.Warn("Stopping extraction, too much data", "file", f.Name, "archive", archive, "max_size", maxExtractSize)
+			break
+		}
+		target := filepath.Join(dest, name)
+		n, err := extractFile(f, target, min(jotti.MaxUploadSize, maxExtractSize-total))
+		if err != nil {
+			slog.Error("Error extracting", "file", f.Name, "archive", archive, "error", err)
+			continue
+		}
+		count++
+		total += n
+		if !fn(fileInput{path: target, name: archive + ":" + f.Name}) {
+			break
+		}
+	}
+	return count, nil
+}
+
+// write a single entry of at most limit bytes to target, the declared size isn't trusted
+func extractFile(f *zip.File, target string, limit int64) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(target), 0o700); err != nil {
+		return 0, err
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(out, io.LimitReader(rc, limit+1))
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && n > limit {
+		err = fmt.Errorf("entry larger than declared size, over %d bytes", limit)
+	}
+	if err != nil {
+		os.Remove(target)
+		return 0, err
+	}
+	return n, nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// zip of the given entries in a temp directory
+func writeZip(t *testing.T, entries map[string]string, order ...string) string {
+	t.Helper()
+	archive := filepath.Join(t.TempDir(), "sample.zip")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for _, name := range order {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(entries[name])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return archive
+}
+
+func extractAll(t *testing.T, archive string) (*scratchDir, []fileInput) {
+	t.Helper()
+	scratch := &scratchDir{}
+	t.Cleanup(scratch.cleanup)
+	var files []fileInput
+	n, err := scratch.extract(archive, func(in fileInput) bool {
+		files = append(files, in)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(files) {
+		t.Errorf("extract reported %d files, handed out %d", n, len(files))
+	}
+	return scratch, files
+}
+
+func TestExtractZipSlip(t *testing.T) {
+	entries := map[string]string{
+		"../evil.txt":     "outside",
+		"/abs/evil.txt":   "absolute",
+		"dir/../../x.txt": "outside",
+		"dir/good.txt":    "inside",
+	}
+	archive := writeZip(t, entries, "../evil.txt", "/abs/evil.txt", "dir/../../x.txt", "dir/good.txt")
+	scratch, files := extractAll(t, archive)
+	if len(files) != 1 || files[0].name != archive+":dir/good.txt" {
+		t.Fatalf("files = %+v, want only dir/good.txt", files)
+	}
+	if !strings.HasPrefix(files[0].path, scratch.dir) {
+		t.Errorf("extracted to %s, outside %s", files[0].path, scratch.dir)
+	}
+	// nothing may land next to the scratch directory or the archive
+	for _, dir := range []string{filepath.Dir(scratch.dir), filepath.Dir(archive)} {
+		if _, err := os.Stat(filepath.Join(dir, "evil.txt")); err == nil {
+			t.Errorf("evil.txt written to %s", dir)
+		}
+	}
+}
+
+func TestExtractSizeCap(t *testing.T) {
+	defer func(old int64) { maxExtractSize = old }(maxExtractSize)
+	maxExtractSize = 10
+
+	entries := map[string]string{"a": "12345", "b": "12345", "c": "1"}
+	_, files := extractAll(t, writeZip(t, entries, "a", "b", "c"))
+	if len(files) != 2 {
+		t.Fatalf("extracted %d files, want 2 within the 10 byte cap", len(files))
+	}
+	for _, f := range files {
+		if strings.HasSuffix(f.name, ":c") {
+			t.Errorf("entry past the size cap was extracted: %s", f.name)
+		}
+	}
+}
+
+func TestExtractFileCap(t *testing.T) {
+	defer func(old int) { maxExtractFiles = old }(maxExtractFiles)
+	maxExtractFiles = 2
+
+	entries := map[string]string{"a": "1", "b": "2", "c": "3"}
+	_, files := extractAll(t, writeZip(t, entries, "a", "b", "c"))
+	if len(files) != 2 {
+		t.Errorf("extracted %d files, want 2", len(files))
+	}
+}
+
+func TestExtractStopsWhenRefused(t *testing.T) {
+	archive := writeZip(t, map[string]string{"a": "1", "b": "2"}, "a", "b")
+	scratch := &scratchDir{}
+	defer scratch.cleanup()
+	n, err := scratch.extract(archive, func(fileInput) bool { return false })
+	if err != nil || n != 1 {
+		t.Fatalf("extract = %d, %v, want 1 file", n, err)
+	}
+	// b was never written, -max-files stops extraction rather than only queueing
+	matches, _ := filepath.Glob(filepath.Join(scratch.dir, "*", "b"))
+	if len(matches) != 0 {
+		t.Errorf("entry past the refusal was extracted: %v", matches)
+	}
+}
@@ -1,3 +1,7 @@
 module github.com/cyclone-github/jotti
 
 go 1.26.2
+
+require golang.org/x/term v0.46.0
+
+require golang.org/x/sys v0.48.0 // indirect
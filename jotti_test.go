@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cyclone-github/jotti/pkg/jotti"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+	}{
+		{"0", 0},
+		{"512", 512},
+		{"1k", 1 << 10},
+		{"1KB", 1 << 10},
+		{" 10 MB ", 10 << 20},
+		{"250m", 250 << 20},
+		{"1G", 1 << 30},
+		{"3b", 3},
+	}
+	for _, tt := range tests {
+		got, err := parseSize(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("parseSize(%q) = %d, %v, want %d", tt.value, got, err, tt.want)
+		}
+	}
+	for _, value := range []string{"", "-1", "1.5M", "ten", "1T", "M"} {
+		if _, err := parseSize(value); err == nil {
+			t.Errorf("parseSize(%q) accepted", value)
+		}
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[float64]string{
+		0:                 "0 B",
+		1023:              "1023 B",
+		1024:              "1.0 KB",
+		1.5 * (1 << 20):   "1.5 MB",
+		142.5 * (1 << 20): "142.5 MB",
+		3 * (1 << 30):     "3.0 GB",
+	}
+	for n, want := range tests {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%v) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestParseDurationFlag(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"90s", 90 * time.Second},
+		{"0", 0},
+		{"1h30m", 90 * time.Minute},
+		{"soon", time.Minute},
+		{"-5s", time.Minute},
+	}
+	for _, tt := range tests {
+		if got := parseDurationFlag("test", tt.value, time.Minute); got != tt.want {
+			t.Errorf("parseDurationFlag(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+// sequential searches with -keep-alive 90s against -keep-alive 0, which dials every request
+func BenchmarkKeepAlive(b *testing.B) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `<div class="notfound">Hash not found</div>`)
+	}))
+	defer srv.Close()
+
+	for _, idle := range []time.Duration{90 * time.Second, 0} {
+		b.Run("keep-alive="+idle.String(), func(b *testing.B) {
+			transport := http.DefaultTransport.(*http.Transport).Clone()
+			configureKeepAlive(transport, idle, 1)
+			defer transport.CloseIdleConnections()
+			client := &http.Client{Transport: transport}
+			for b.Loop() {
+				resp, err := client.Get(srv.URL)
+				if err != nil {
+					b.Fatal(err)
+				}
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+			}
+		})
+	}
+}
+
+func TestWalkDirSymlinkRoot(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "real", "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.bin", filepath.Join("sub", "b.bin")} {
+		if err := os.WriteFile(filepath.Join(dir, "real", name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink("real", link); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+	want := []string{filepath.Join(link, "a.bin"), filepath.Join(link, "sub", "b.bin")}
+	for _, follow := range []bool{false, true} {
+		var got []string
+		walkDir(link, follow, func(p string) bool {
+			got = append(got, p)
+			return true
+		})
+		if !slices.Equal(got, want) {
+			t.Errorf("follow %v: walked %v, want %v", follow, got, want)
+		}
+	}
+}
+
+func TestHashTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sample.bin")
+	if err := os.WriteFile(path, []byte("sample"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// the -file-timeout budget already ran out while hashing
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now())
+	defer cancel()
+	opt := &scanOptions{algo: "sha1"}
+	file := hashFile(ctx, fileInput{path: path}, opt)
+	if !errors.Is(file.hashErr, context.DeadlineExceeded) {
+		t.Fatalf("hashErr = %v, want DeadlineExceeded", file.hashErr)
+	}
+	r := processFile(ctx, file, opt, io.Discard)
+	if !errors.Is(r.err, context.DeadlineExceeded) || r.SHA1 != "" {
+		t.Errorf("result = %+v, want the deadline error", r)
+	}
+}
+
+func TestUnreadableFile(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can read files without permission")
+	}
+	path := filepath.Join(t.TempDir(), "locked.bin")
+	if err := os.WriteFile(path, []byte("sample"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(path, 0o644)
+
+	opt := &scanOptions{algo: "sha1"}
+	r := processFile(context.Background(), hashFile(context.Background(), fileInput{path: path}, opt), opt, io.Discard)
+	if !errors.Is(r.err, fs.ErrPermission) || r.Error == nil || !strings.Contains(*r.Error, "file not readable") {
+		t.Errorf("result = %+v, want a file not readable error", r)
+	}
+	if got := fileExitCode(r, "", false); got != exitError {
+		t.Errorf("fileExitCode = %d, want %d", got, exitError)
+	}
+}
+
+func TestHashOnlyIgnoresSizeLimits(t *testing.T) {
+	defer func(old int64) { jotti.MaxUploadSize = old }(jotti.MaxUploadSize)
+	jotti.MaxUploadSize = 10
+
+	dir := t.TempDir()
+	big := filepath.Join(dir, "big.bin")
+	empty := filepath.Join(dir, "empty.bin")
+	if err := os.WriteFile(big, []byte("over the ten byte limit"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(empty, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	opt := &scanOptions{algo: "sha1", hashOnly: true, minSize: 1}
+	for _, path := range []string{big, empty} {
+		r := processFile(context.Background(), hashFile(context.Background(), fileInput{path: path}, opt), opt, io.Discard)
+		if r.Error != nil || !r.HashOnly || r.SkipReason != "" || r.SHA1 == "" {
+			t.Errorf("%s: result = %+v, want hashed", filepath.Base(path), r)
+		}
+		if path == empty && r.SHA1 != "da39a3ee5e6b4b0d3255bfef95601890afd80709" {
+			t.Errorf("empty file SHA1 = %s", r.SHA1)
+		}
+	}
+
+	// a scan still skips the oversize file before hashing it
+	opt.hashOnly = false
+	r := processFile(context.Background(), hashFile(context.Background(), fileInput{path: big}, opt), opt, io.Discard)
+	if !errors.Is(r.err, jotti.ErrFileTooLarge) {
+		t.Errorf("scan of an oversize file: err = %v, want ErrFileTooLarge", r.err)
+	}
+}
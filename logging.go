@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// true with -log-format json, each result is then also logged as a record
+var jsonLogs bool
+
+// route log and slog output for -log-format and -logfile
+func setupLogging(format string, logFile io.Writer) error {
+	switch format {
+	case "text":
+		// slog's default handler writes through log, so the console format and logTee are kept
+		log.SetOutput(stderr)
+		if logFile != nil {
+			log.SetFlags(0)
+			log.SetOutput(&logTee{console: stderr, file: logFile})
+		}
+	case "json":
+		var w io.Writer = stderr
+		if logFile != nil {
+			w = io.MultiWriter(stderr, logFile)
+		}
+		// classic log calls become INFO records of the same handler
+		slog.SetDefault(slog.New(slog.NewJSONHandler(w, nil)))
+		jsonLogs = true
+	default:
+		return fmt.Errorf("unknown format %q (use text or json)", format)
+	}
+	return nil
+}
+
+// one record per scanned file with -log-format json, the console already shows results otherwise
+func logResult(r scanResult) {
+	if !jsonLogs {
+		return
+	}
+	attrs := []any{"file", r.File, "status", r.status()}
+	if r.SHA1 != "" {
+		attrs = append(attrs, "sha1", r.SHA1)
+	}
+	if r.JottiURL != "" {
+		attrs = append(attrs, "url", r.JottiURL)
+	}
+	if r.SkipReason != "" {
+		attrs = append(attrs, "reason", r.SkipReason)
+	}
+	if len(r.Engines) > 0 {
+		attrs = append(attrs, "detected", r.Detected, "engines", len(r.Engines))
+	}
+	if r.err != nil {
+		slog.Error("Scanned", append(attrs, "error", r.err)...)
+		return
+	}
+	slog.Info("Scanned", attrs...)
+}
+
+// run totals as the last record with -log-format json
+func logSummary(s *runStats, elapsed time.Duration) {
+	if !jsonLogs {
+		return
+	}
+	slog.Info("Summary", "scanned", s.scanned, "found", s.found, "uploaded", s.uploaded, "detected", s.detected, "errors", s.errors,
+		"bytes", s.bytes, "uploaded_bytes", s.uploadedBytes, "elapsed", elapsed.Seconds())
+}
+
+// adapt slog to the Logf callbacks of pkg/jotti, one record per message
+func slogf(level slog.Level) func(format string, v ...any) {
+	return func(format string, v ...any) {
+		slog.Log(context.Background(), level, strings.TrimSpace(fmt.Sprintf(format, v...)))
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+)
+
+// completed file recorded in a -manifest
+type manifestEntry struct {
+	File   string `json:"file"`
+	SHA1   string `json:"sha1"`
+	Status string `json:"status"`
+}
+
+// append-only log of finished files for resuming a batch, a nil manifest is disabled
+type manifest struct {
+	mu   sync.Mutex
+	file *os.File
+	done map[string]bool
+}
+
+// load finished files from path and open it for appending
+func openManifest(path string) (*manifest, error) {
+	m := &manifest{done: make(map[string]bool)}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		var e manifestEntry
+		// partial lines from an interrupted run are ignored
+		if json.Unmarshal(line, &e) != nil || e.File == "" {
+			continue
+		}
+		m.done[e.File] = true
+	}
+
+	if m.file, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644); err != nil {
+		return nil, err
+	}
+	// terminate a truncated last line so new entries start cleanly
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		if _, err := m.file.Write([]byte("\n")); err != nil {
+			m.file.Close()
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// report whether filePath finished in a previous run
+func (m *manifest) has(filePath string) bool {
+	if m == nil {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.done[filePath]
+}
+
+// record a finished file, failed, dry-run and hash-only files are left out so they are retried
+func (m *manifest) add(r scanResult) error {
+	if m == nil || r.Error != nil || r.DryRun || r.HashOnly {
+		return nil
+	}
+	line, err := json.Marshal(manifestEntry{File: r.File, SHA1: r.SHA1, Status: r.status()})
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.done[r.File] = true
+	_, err = m.file.Write(append(line, '\n'))
+	return err
+}
+
+func (m *manifest) close() error {
+	if m == nil {
+		return nil
+	}
+	return m.file.Close()
+}
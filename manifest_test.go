@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestManifest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "done.jsonl")
+	m, err := openManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed := scanResult{File: "failed.bin"}
+	failed.setError(errors.New("boom"))
+	for _, r := range []scanResult{
+		{File: "found.bin", SHA1: "aa", Found: true},
+		{File: "z.zip:a.bin", SHA1: "bb", Uploaded: true},
+		failed,
+		{File: "dry.bin", DryRun: true},
+		{File: "hashed.bin", HashOnly: true},
+	} {
+		if err := m.add(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !m.has("found.bin") {
+		t.Error("has(found.bin) = false after add")
+	}
+	if err := m.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err = openManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.close()
+	for file, want := range map[string]bool{
+		"found.bin":   true,
+		"z.zip:a.bin": true,
+		"failed.bin":  false,
+		"dry.bin":     false,
+		"hashed.bin":  false,
+	} {
+		if got := m.has(file); got != want {
+			t.Errorf("has(%q) = %v, want %v", file, got, want)
+		}
+	}
+}
+
+func TestManifestTruncatedLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "done.jsonl")
+	// interrupted while writing the second entry
+	if err := os.WriteFile(path, []byte(`{"file":"a","sha1":"aa","status":"FOUND"}`+"\n"+`{"file":"b","sh`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := openManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.has("a") || m.has("b") {
+		t.Errorf("has(a) = %v, has(b) = %v, want true, false", m.has("a"), m.has("b"))
+	}
+	if err := m.add(scanResult{File: "c", Found: true}); err != nil {
+		t.Fatal(err)
+	}
+	m.close()
+
+	m, err = openManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.close()
+	if !m.has("c") {
+		data, _ := os.ReadFile(path)
+		t.Errorf("entry after a truncated line was lost:\n%s", strings.TrimSpace(string(data)))
+	}
+}
+
+func TestManifestNil(t *testing.T) {
+	var m *manifest
+	if m.has("x") || m.add(scanResult{File: "x"}) != nil || m.close() != nil {
+		t.Error("disabled manifest did something")
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// one gauge in the -metrics-file
+type metric struct {
+	name, help string
+	value      float64
+}
+
+// run totals in the Prometheus text exposition format
+func formatMetrics(s *runStats, elapsed time.Duration, now time.Time) string {
+	metrics := []metric{
+		{"jotti_files_processed", "Files processed in the last run.", float64(s.scanned)},
+		{"jotti_files_found", "Files already known to Jotti in the last run.", float64(s.found)},
+		{"jotti_files_uploaded", "Files uploaded to Jotti in the last run.", float64(s.uploaded)},
+		{"jotti_files_errors", "Files that failed in the last run.", float64(s.errors)},
+		{"jotti_bytes_processed", "Total size in bytes of the files processed in the last run.", float64(s.bytes)},
+		{"jotti_bytes_uploaded", "Total size in bytes of the files uploaded in the last run.", float64(s.uploadedBytes)},
+		{"jotti_run_duration_seconds", "Wall-clock duration of the last run.", elapsed.Seconds()},
+		{"jotti_last_run_timestamp_seconds", "Unix time the last run finished.", float64(now.Unix())},
+	}
+	var b strings.Builder
+	for _, m := range metrics {
+		fmt.Fprintf(&b, "# HELP %s %s\n", m.name, m.help)
+		fmt.Fprintf(&b, "# TYPE %s gauge\n", m.name)
+		fmt.Fprintf(&b, "%s %s\n", m.name, strconv.FormatFloat(m.value, 'f', -1, 64))
+	}
+	return b.String()
+}
+
+// write metrics for node_exporter's textfile collector, via a temp file and rename
+// so a scrape never sees a half-written file
+func writeMetrics(path string, s *runStats, elapsed time.Duration) error {
+	f, err := os.CreateTemp(filepath.Dir(path), ".jotti-metrics-")
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(formatMetrics(s, elapsed, time.Now())); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	// CreateTemp makes the file 0600, the collector may run as another user
+	if err := os.Chmod(f.Name(), 0o644); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
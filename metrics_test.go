@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatMetrics(t *testing.T) {
+	want, err := os.ReadFile(filepath.Join("testdata", "metrics.prom"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &runStats{scanned: 5, found: 2, uploaded: 2, errors: 1, bytes: 1 << 20, uploadedBytes: 4096}
+	now := time.Date(2024, 3, 5, 14, 7, 9, 0, time.UTC)
+	got := formatMetrics(s, 12500*time.Millisecond, now)
+	if got != string(want) {
+		t.Errorf("formatMetrics =\n%s\nwant\n%s", got, want)
+	}
+	// the textfile collector rejects a file whose last line isn't terminated
+	if !strings.HasSuffix(got, "\n") || strings.HasSuffix(got, "\n\n") {
+		t.Errorf("metrics end in %q, want a single newline", got[len(got)-2:])
+	}
+}
+
+func TestWriteMetrics(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jotti.prom")
+	if err := writeMetrics(path, &runStats{scanned: 1}, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0o644 {
+		t.Errorf("mode = %v, want 0644", fi.Mode().Perm())
+	}
+	matches, _ := filepath.Glob(filepath.Join(filepath.Dir(path), ".jotti-metrics-*"))
+	if len(matches) != 0 {
+		t.Errorf("temp files left behind: %v", matches)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// one lock for stdout and stderr, they usually share a terminal
+var outputMu sync.Mutex
+
+// stdout and stderr for everything printed once files are being processed, so lines
+// from workers, the file feeder and the log never interleave mid-write
+var (
+	stdout = &syncWriter{mu: &outputMu, w: os.Stdout}
+	stderr = &syncWriter{mu: &outputMu, w: os.Stderr}
+)
+
+// writer serializing Writes from several goroutines, a whole block written at once
+// (such as a worker's buffered output for one file) stays together
+type syncWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+// file descriptor of the underlying file, lets the progress bar read the terminal width
+func (s *syncWriter) Fd() uintptr {
+	if f, ok := s.w.(*os.File); ok {
+		return f.Fd()
+	}
+	return ^uintptr(0)
+}
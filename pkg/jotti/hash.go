@@ -0,0 +1,111 @@
+package jotti
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+)
+
+// supported checksum algorithms
+var hashAlgos = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+}
+
+// HashBufferSize is the read buffer used by CalculateChecksums and HashReader, 0 keeps io.Copy's 32KB default.
+// A larger buffer (e.g. 1MB) reduces syscalls when hashing large files.
+var HashBufferSize = 0
+
+// HashAlgorithms lists the supported checksum algorithms in display order
+var HashAlgorithms = []string{"md5", "sha1", "sha256"}
+
+// IsSupportedHash reports whether algo is a supported checksum algorithm
+func IsSupportedHash(algo string) bool {
+	_, ok := hashAlgos[algo]
+	return ok
+}
+
+// HashType returns the algorithm a hex digest of s's length belongs to, or "" if s is not a hex digest
+func HashType(s string) string {
+	if _, err := hex.DecodeString(s); err != nil {
+		return ""
+	}
+	for algo, newHash := range hashAlgos {
+		if len(s) == newHash().Size()*2 {
+			return algo
+		}
+	}
+	return ""
+}
+
+// CalculateChecksums returns all supported checksums of file in a single pass, keyed by algorithm
+func CalculateChecksums(filePath string) (map[string]string, error) {
+	return CalculateChecksumsContext(context.Background(), filePath)
+}
+
+// CalculateChecksumsContext is CalculateChecksums, but stops reading and returns ctx.Err() once ctx is done
+func CalculateChecksumsContext(ctx context.Context, filePath string) (map[string]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return checksums(&ctxReader{ctx: ctx, r: file}, HashAlgorithms...)
+}
+
+// reads from r until ctx is done
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *ctxReader) Read(b []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(b)
+}
+
+// HashReader returns the hex digest of everything read from r, for data that isn't in a file
+func HashReader(r io.Reader, algo string) (string, error) {
+	if !IsSupportedHash(algo) {
+		return "", fmt.Errorf("unsupported hash algorithm %q", algo)
+	}
+	sums, err := checksums(r, algo)
+	if err != nil {
+		return "", err
+	}
+	return sums[algo], nil
+}
+
+// read r once, feeding every algo
+func checksums(r io.Reader, algos ...string) (map[string]string, error) {
+	hashes := make(map[string]hash.Hash, len(algos))
+	writers := make([]io.Writer, 0, len(algos))
+	for _, algo := range algos {
+		h := hashAlgos[algo]()
+		hashes[algo] = h
+		writers = append(writers, h)
+	}
+	var buf []byte
+	if HashBufferSize > 0 {
+		buf = make([]byte, HashBufferSize)
+	}
+	// hide WriteTo (e.g. *os.File), CopyBuffer would otherwise ignore buf
+	if _, err := io.CopyBuffer(io.MultiWriter(writers...), struct{ io.Reader }{r}, buf); err != nil {
+		return nil, err
+	}
+
+	checksums := make(map[string]string, len(hashes))
+	for algo, h := range hashes {
+		checksums[algo] = hex.EncodeToString(h.Sum(nil))
+	}
+	return checksums, nil
+}
@@ -0,0 +1,109 @@
+package jotti
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// digests of "abc"
+var abcSums = map[string]string{
+	"md5":    "900150983cd24fb0d6963f7d28e17f72",
+	"sha1":   "a9993e364706816aba3e25717850c26c9cd0d89d",
+	"sha256": "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+}
+
+func TestHashReader(t *testing.T) {
+	for algo, want := range abcSums {
+		// one byte reads must hash the same as a single read
+		got, err := HashReader(iotest.OneByteReader(strings.NewReader("abc")), algo)
+		if err != nil || got != want {
+			t.Errorf("HashReader %s = %q, %v, want %q", algo, got, err, want)
+		}
+	}
+	if _, err := HashReader(strings.NewReader("abc"), "crc32"); err == nil {
+		t.Error("HashReader accepted crc32")
+	}
+	if _, err := HashReader(iotest.ErrReader(os.ErrClosed), "md5"); err == nil {
+		t.Error("HashReader ignored a read error")
+	}
+}
+
+func TestCalculateChecksums(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "abc")
+	if err := os.WriteFile(path, []byte("abc"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []int{0, 1, 4096} {
+		HashBufferSize = size
+		sums, err := CalculateChecksums(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for algo, want := range abcSums {
+			if sums[algo] != want {
+				t.Errorf("buffer %d: %s = %q, want %q", size, algo, sums[algo], want)
+			}
+		}
+	}
+	HashBufferSize = 0
+}
+
+func TestCalculateChecksumsContext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "abc")
+	if err := os.WriteFile(path, []byte("abc"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := CalculateChecksumsContext(ctx, path); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	sums, err := CalculateChecksumsContext(context.Background(), path)
+	if err != nil || sums["sha1"] != abcSums["sha1"] {
+		t.Errorf("sha1 = %q, %v, want %q", sums["sha1"], err, abcSums["sha1"])
+	}
+}
+
+func TestHashType(t *testing.T) {
+	tests := map[string]string{
+		abcSums["md5"]:                  "md5",
+		abcSums["sha1"]:                 "sha1",
+		abcSums["sha256"]:               "sha256",
+		strings.ToUpper(abcSums["md5"]): "md5",
+		"abc":                           "",
+		strings.Repeat("g", 32):         "",
+		strings.Repeat("a", 30):         "",
+	}
+	for s, want := range tests {
+		if got := HashType(s); got != want {
+			t.Errorf("HashType(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
+
+// compare -hash-buffer sizes on a file in the page cache
+func BenchmarkCalculateChecksums(b *testing.B) {
+	path := filepath.Join(b.TempDir(), "sample")
+	if err := os.WriteFile(path, bytes.Repeat([]byte{0x5A}, 16<<20), 0o644); err != nil {
+		b.Fatal(err)
+	}
+	defer func(old int) { HashBufferSize = old }(HashBufferSize)
+	for _, size := range []int{0, 256 << 10, 1 << 20, 4 << 20} {
+		b.Run(fmt.Sprintf("buffer=%dKB", size>>10), func(b *testing.B) {
+			HashBufferSize = size
+			b.SetBytes(16 << 20)
+			for b.Loop() {
+				if _, err := CalculateChecksums(path); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,584 @@
+// Package jotti uploads files to and searches hashes on https://virusscan.jotti.org
+package jotti
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// default Jotti endpoints
+const (
+	DefaultUploadURL = "https://virusscan.jotti.org/en-US/submit-file"
+	DefaultSearchURL = "https://virusscan.jotti.org/en-US/search/hash/%s"
+	DefaultUserAgent = "jotti (+github.com/cyclone-github/jotti)"
+	DefaultFormField = "sample-file[]"
+)
+
+// DefaultLocale is the locale segment of the default endpoints
+const DefaultLocale = "en-US"
+
+// LocalizeURL replaces the /en-US/ path segment of a Jotti URL with locale, e.g. "de-DE"
+func LocalizeURL(rawURL, locale string) string {
+	return strings.Replace(rawURL, "/"+DefaultLocale+"/", "/"+locale+"/", 1)
+}
+
+// MaxUploadSize is Jotti's upload limit in bytes, larger files are rejected before any request
+var MaxUploadSize int64 = 250 * 1024 * 1024
+
+var (
+	// ErrRateLimited is returned when Jotti rate limits a request and all retries are exhausted
+	ErrRateLimited = errors.New("rate limited by Jotti")
+	// ErrFileTooLarge is returned for files above Jotti's upload limit
+	ErrFileTooLarge = errors.New("file too large")
+	// ErrBlocked is returned when Jotti serves a CAPTCHA, challenge or maintenance page instead of results
+	ErrBlocked = errors.New("blocked by CAPTCHA or interstitial page")
+	// ErrInvalidHash is returned by SearchHash for input that is not an MD5, SHA1 or SHA256 hex digest
+	ErrInvalidHash = errors.New("invalid hash")
+	// ErrRedirected is returned when Jotti redirects a page request and redirects are not followed
+	ErrRedirected = errors.New("unexpected redirect")
+)
+
+// markers of CAPTCHA, bot challenge and maintenance pages
+var blockedMarkers = []string{
+	"g-recaptcha",
+	"h-captcha",
+	"hcaptcha.com",
+	"cf-challenge",
+	"challenge-platform",
+	"cf-browser-verification",
+	"<title>just a moment...</title>",
+	"attention required! | cloudflare",
+	"under maintenance",
+}
+
+// report whether body looks like an interstitial page rather than Jotti content
+func isBlockedPage(body string) bool {
+	return containsAny(body, blockedMarkers)
+}
+
+// RateLimitError is returned by a rate limited request, it matches ErrRateLimited
+type RateLimitError struct {
+	RetryAfter time.Duration // server requested wait from Retry-After, 0 if absent
+}
+
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%v, retry after %s", ErrRateLimited, e.RetryAfter)
+	}
+	return ErrRateLimited.Error()
+}
+
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// FileTooLargeError is returned for files above MaxUploadSize, it matches ErrFileTooLarge
+type FileTooLargeError struct {
+	Size int64 // size of the rejected file or batch
+	Max  int64 // limit in effect when it was rejected
+}
+
+func (e *FileTooLargeError) Error() string {
+	return fmt.Sprintf("%v: size %d exceeds %d byte limit", ErrFileTooLarge, e.Size, e.Max)
+}
+
+func (e *FileTooLargeError) Is(target error) bool {
+	return target == ErrFileTooLarge
+}
+
+// rate limit error honoring the response's Retry-After header
+func rateLimited(response *http.Response) error {
+	return &RateLimitError{RetryAfter: parseRetryAfter(response.Header.Get("Retry-After"), time.Now())}
+}
+
+// parse Retry-After in either delay-seconds or HTTP-date form
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// HTTPStatusError is returned when Jotti responds with an unexpected status code
+type HTTPStatusError struct {
+	StatusCode int
+	Body       string // start of the response body as text, may be empty
+}
+
+func (e *HTTPStatusError) Error() string {
+	if e.Body != "" {
+		return fmt.Sprintf("unexpected response status: %d: %s", e.StatusCode, e.Body)
+	}
+	return fmt.Sprintf("unexpected response status: %d", e.StatusCode)
+}
+
+// longest body snippet kept in HTTPStatusError
+const maxErrorBody = 500
+
+// HTTPStatusError for response, with the start of its body so rejections explain themselves
+func statusError(response *http.Response) error {
+	raw, _ := readBody(response, 64<<10)
+	return &HTTPStatusError{StatusCode: response.StatusCode, Body: bodySnippet(string(raw))}
+}
+
+// response body as text, 0 reads it all. Go only decompresses gzip by itself when
+// it asked for it, so a body still marked gzip (Accept-Encoding set by hand) is decoded here.
+func readBody(response *http.Response, limit int64) ([]byte, error) {
+	var r io.Reader = response.Body
+	if !response.Uncompressed && strings.EqualFold(response.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(response.Body)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	if limit > 0 {
+		r = io.LimitReader(r, limit)
+	}
+	return io.ReadAll(r)
+}
+
+// page text without scripts or markup, cut to maxErrorBody bytes
+func bodySnippet(s string) string {
+	s = htmlText(scriptRegex.ReplaceAllString(s, " "))
+	if len(s) <= maxErrorBody {
+		return s
+	}
+	return strings.ToValidUTF8(s[:maxErrorBody], "") + "..."
+}
+
+// Client talks to Jotti
+type Client struct {
+	HTTPClient *http.Client
+	UploadURL  string      // multipart form submit endpoint
+	SearchURL  string      // hash search endpoint, %s is replaced by the hash
+	UserAgent  string      // User-Agent header, empty keeps Go's default
+	FormField  string      // multipart field of each uploaded file, empty uses DefaultFormField
+	Header     http.Header // extra headers sent with every request, e.g. for header-based gateways
+
+	MaxRetries   int           // retries when rate limited
+	RetryWait    time.Duration // base backoff, doubled on each retry
+	MaxRetryWait time.Duration // backoff cap
+
+	// Limiter paces all requests, nil disables it
+	Limiter *Limiter
+	// UploadLimiter caps upload bandwidth across all uploads, nil disables it
+	UploadLimiter *ByteLimiter
+
+	// Progress receives the upload progress bar, nil disables it
+	Progress io.Writer
+	// ProgressColor draws the filled part of the progress bar in green using ANSI codes
+	ProgressColor bool
+	// Logf receives diagnostic messages such as retries, nil disables them
+	Logf func(format string, v ...any)
+}
+
+// NewClient returns a Client with Jotti's default endpoints and options
+func NewClient() *Client {
+	return &Client{
+		HTTPClient:   &http.Client{Timeout: 30 * time.Second},
+		UploadURL:    DefaultUploadURL,
+		SearchURL:    DefaultSearchURL,
+		UserAgent:    DefaultUserAgent,
+		FormField:    DefaultFormField,
+		MaxRetries:   3,
+		RetryWait:    5 * time.Second,
+		MaxRetryWait: 2 * time.Minute,
+	}
+}
+
+// SearchResult of a hash lookup
+type SearchResult struct {
+	Found bool
+	URL   string
+	Body  string // results page body when found
+}
+
+// Search checks if hash exists on Jotti
+func (c *Client) Search(ctx context.Context, hash string) (SearchResult, error) {
+	var result SearchResult
+	err := c.withRetry(ctx, func() error {
+		var err error
+		result, err = c.search(ctx, hash)
+		return err
+	})
+	return result, err
+}
+
+// SearchHash checks if an MD5, SHA1 or SHA256 hex digest is known to Jotti
+func (c *Client) SearchHash(ctx context.Context, hash string) (found bool, url string, err error) {
+	if HashType(hash) == "" {
+		return false, "", fmt.Errorf("%w: %q", ErrInvalidHash, hash)
+	}
+	result, err := c.Search(ctx, strings.ToLower(hash))
+	return result.Found, result.URL, err
+}
+
+// HashURL is the search page for hash, escaped so odd input stays in its path segment
+func (c *Client) HashURL(hash string) string {
+	escaped := url.PathEscape(hash)
+	// PathEscape leaves dot segments alone, servers would resolve them
+	if escaped == "." || escaped == ".." {
+		escaped = strings.ReplaceAll(escaped, ".", "%2E")
+	}
+	return fmt.Sprintf(c.SearchURL, escaped)
+}
+
+func (c *Client) search(ctx context.Context, hash string) (SearchResult, error) {
+	searchURL := c.HashURL(hash)
+
+	body, err := c.fetchPage(ctx, searchURL)
+	if err != nil {
+		return SearchResult{}, err
+	}
+
+	switch ClassifyPage(body) {
+	case PageNotFound:
+		return SearchResult{URL: searchURL}, nil
+	case PageResults:
+		return SearchResult{Found: true, URL: searchURL, Body: body}, nil
+	}
+	// don't guess, a layout change must not turn into "found"
+	return SearchResult{}, fmt.Errorf("%w at %s", ErrUnrecognizedPage, searchURL)
+}
+
+// Report fetches a results page and parses its verdicts and scan date
+func (c *Client) Report(ctx context.Context, pageURL string) (Report, error) {
+	var body string
+	err := c.withRetry(ctx, func() error {
+		var err error
+		body, err = c.fetchPage(ctx, pageURL)
+		return err
+	})
+	if err != nil {
+		return Report{URL: pageURL}, err
+	}
+	return ParseReport(pageURL, body)
+}
+
+// WaitForResults polls a results page every interval until no engine is still scanning.
+// When ctx ends first, the report holds the last verdicts seen together with ctx's error.
+func (c *Client) WaitForResults(ctx context.Context, pageURL string, interval time.Duration) (Report, error) {
+	for {
+		report, err := c.Report(ctx, pageURL)
+		// results table may not be rendered until the scan starts
+		if err != nil && !errors.Is(err, ErrNoScanResults) {
+			return report, err
+		}
+		if err == nil && !report.Pending {
+			return report, nil
+		}
+		if c.Logf != nil {
+			c.Logf("Scan in progress, checking again in %s\n", interval)
+		}
+		if err := sleepContext(ctx, interval); err != nil {
+			return report, err
+		}
+	}
+}
+
+// Upload submits file at filePath to Jotti and returns the scan job URL from the
+// response, "" when Jotti didn't link one and the hash search URL has to do
+func (c *Client) Upload(ctx context.Context, filePath string) (string, error) {
+	return c.UploadWithProgress(ctx, filePath, nil)
+}
+
+// UploadWithProgress is Upload calling progress as the file is sent, in place of
+// the Progress bar. A retried upload starts again from 0.
+func (c *Client) UploadWithProgress(ctx context.Context, filePath string, progress ProgressFunc) (string, error) {
+	var resultURL string
+	err := c.withRetry(ctx, func() error {
+		var err error
+		resultURL, err = c.upload(ctx, progress, filePath)
+		return err
+	})
+	return resultURL, err
+}
+
+// UploadBatch submits several files to Jotti in a single multipart request, the
+// returned scan job URL covers all of them
+func (c *Client) UploadBatch(ctx context.Context, filePaths []string) (string, error) {
+	var resultURL string
+	err := c.withRetry(ctx, func() error {
+		var err error
+		resultURL, err = c.upload(ctx, nil, filePaths...)
+		return err
+	})
+	return resultURL, err
+}
+
+func (c *Client) upload(ctx context.Context, progress ProgressFunc, filePaths ...string) (string, error) {
+	files := make([]*os.File, 0, len(filePaths))
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+
+	var sizes []int64
+	var names []string
+	var total int64
+	for _, filePath := range filePaths {
+		file, err := os.Open(filePath)
+		if err != nil {
+			return "", err
+		}
+		files = append(files, file)
+
+		fi, err := file.Stat()
+		if err != nil {
+			return "", err
+		}
+		sizes = append(sizes, fi.Size())
+		names = append(names, filepath.Base(filePath))
+		total += fi.Size()
+	}
+	// reject oversize uploads before touching the network
+	if total > MaxUploadSize {
+		return "", &FileTooLargeError{Size: total, Max: MaxUploadSize}
+	}
+
+	// stream multipart body from disk instead of buffering the files in memory
+	pipeReader, pipeWriter := io.Pipe()
+	writer := multipart.NewWriter(pipeWriter)
+
+	field := c.FormField
+	if field == "" {
+		field = DefaultFormField
+	}
+	contentLength, err := multipartLength(writer.Boundary(), field, names, total)
+	if err != nil {
+		return "", err
+	}
+
+	// an explicit callback replaces the terminal bar
+	if progress == nil && c.Progress != nil {
+		bar := &progressBar{w: c.Progress, color: c.ProgressColor}
+		progress = bar.update
+	}
+	var pr *progressReader
+	if progress != nil {
+		pr = &progressReader{total: total, fn: progress}
+	}
+
+	go func() {
+		for i, file := range files {
+			part, err := writer.CreateFormFile(field, names[i])
+			if err != nil {
+				pipeWriter.CloseWithError(err)
+				return
+			}
+			var src io.Reader = file
+			// throttle below the progress bar so it shows bytes actually sent
+			if c.UploadLimiter != nil {
+				src = &throttledReader{ctx: ctx, r: src, limiter: c.UploadLimiter}
+			}
+			if pr != nil {
+				pr.r = src
+				src = pr
+			}
+			if _, err = io.Copy(part, src); err != nil {
+				pipeWriter.CloseWithError(err)
+				return
+			}
+		}
+		pipeWriter.CloseWithError(writer.Close())
+	}()
+
+	request, err := http.NewRequestWithContext(ctx, "POST", c.UploadURL, pipeReader)
+	if err != nil {
+		pipeReader.Close()
+		return "", err
+	}
+	request.Header.Add("Content-Type", writer.FormDataContentType())
+	request.ContentLength = contentLength
+	c.setHeaders(request)
+
+	if err := c.wait(ctx); err != nil {
+		pipeReader.Close()
+		return "", err
+	}
+	response, err := c.HTTPClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusTooManyRequests {
+		return "", rateLimited(response)
+	}
+	// with redirects not followed, the Location of the response is the scan job
+	if isRedirect(response.StatusCode) {
+		return scanJobURL(response, ""), nil
+	}
+	if response.StatusCode != http.StatusOK {
+		return "", statusError(response)
+	}
+
+	bodyBytes, err := readBody(response, 0)
+	if err != nil {
+		return "", err
+	}
+	if isRateLimitPage(string(bodyBytes)) {
+		return "", rateLimited(response)
+	}
+
+	return scanJobURL(response, string(bodyBytes)), nil
+}
+
+// link to the fresh scan in an upload response: the page a redirect ended on,
+// a Location header or a scan job link in the body
+func scanJobURL(response *http.Response, body string) string {
+	final := response.Request.URL
+	if response.Request.Response != nil {
+		// client followed a redirect, the final page is the scan
+		return final.String()
+	}
+	link := response.Header.Get("Location")
+	if link == "" {
+		if m := scanJobRegex.FindStringSubmatch(body); m != nil {
+			link = html.UnescapeString(m[1])
+		}
+	}
+	if link == "" {
+		return ""
+	}
+	u, err := final.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return u.String()
+}
+
+// size of the multipart body, so the request isn't sent chunked
+func multipartLength(boundary, field string, fileNames []string, filesSize int64) (int64, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	if err := writer.SetBoundary(boundary); err != nil {
+		return 0, err
+	}
+	for _, name := range fileNames {
+		if _, err := writer.CreateFormFile(field, name); err != nil {
+			return 0, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return 0, err
+	}
+	return int64(buf.Len()) + filesSize, nil
+}
+
+// User-Agent and Header, an explicit User-Agent in Header wins
+func (c *Client) setHeaders(request *http.Request) {
+	if c.UserAgent != "" {
+		request.Header.Set("User-Agent", c.UserAgent)
+	}
+	for key, values := range c.Header {
+		request.Header[key] = append([]string(nil), values...)
+	}
+}
+
+// fetch page body from Jotti
+func (c *Client) fetchPage(ctx context.Context, pageURL string) (string, error) {
+	request, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return "", err
+	}
+	c.setHeaders(request)
+
+	if err := c.wait(ctx); err != nil {
+		return "", err
+	}
+	response, err := c.HTTPClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusTooManyRequests {
+		return "", rateLimited(response)
+	}
+	if isRedirect(response.StatusCode) {
+		return "", redirectError(response)
+	}
+	if response.StatusCode != http.StatusOK {
+		return "", statusError(response)
+	}
+
+	bodyBytes, err := readBody(response, 0)
+	if err != nil {
+		return "", err
+	}
+	body := string(bodyBytes)
+
+	if isRateLimitPage(body) {
+		return "", rateLimited(response)
+	}
+	if isBlockedPage(body) {
+		return "", ErrBlocked
+	}
+	return body, nil
+}
+
+// call fn again while rate limited, waiting for Retry-After or with exponential backoff
+func (c *Client) withRetry(ctx context.Context, fn func() error) error {
+	wait := c.RetryWait
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if !errors.Is(err, ErrRateLimited) || attempt >= c.MaxRetries {
+			return err
+		}
+		sleep := wait
+		var rl *RateLimitError
+		if errors.As(err, &rl) && rl.RetryAfter > 0 {
+			sleep = rl.RetryAfter
+		}
+		c.logf("Rate limited by Jotti, retrying in %s (%d/%d)\n", sleep, attempt+1, c.MaxRetries)
+		if err := sleepContext(ctx, sleep); err != nil {
+			return err
+		}
+		wait *= 2
+		if c.MaxRetryWait > 0 && wait > c.MaxRetryWait {
+			wait = c.MaxRetryWait
+		}
+	}
+}
+
+func (c *Client) wait(ctx context.Context) error {
+	if c.Limiter == nil {
+		return nil
+	}
+	return c.Limiter.Wait(ctx)
+}
+
+func (c *Client) logf(format string, v ...any) {
+	if c.Logf != nil {
+		c.Logf(format, v...)
+	}
+}
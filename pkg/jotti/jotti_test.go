@@ -0,0 +1,390 @@
+package jotti
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// client pointed at srv with retries that don't slow the tests down
+func newTestClient(srv *httptest.Server) *Client {
+	c := NewClient()
+	c.HTTPClient = srv.Client()
+	c.UploadURL = srv.URL + "/submit"
+	c.SearchURL = srv.URL + "/search/%s"
+	c.RetryWait = time.Millisecond
+	c.MaxRetryWait = 10 * time.Millisecond
+	return c
+}
+
+// server answering every request with body
+func pageServer(t *testing.T, body string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func writeTempFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSearch(t *testing.T) {
+	tests := []struct {
+		fixture string
+		found   bool
+		err     error
+	}{
+		{"results.html", true, nil},
+		{"notfound.html", false, nil},
+	}
+	for _, tt := range tests {
+		srv := pageServer(t, readFixture(t, tt.fixture))
+		result, err := newTestClient(srv).Search(context.Background(), "abc")
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: err = %v, want %v", tt.fixture, err, tt.err)
+			continue
+		}
+		if result.Found != tt.found {
+			t.Errorf("%s: Found = %v, want %v", tt.fixture, result.Found, tt.found)
+		}
+		if err == nil && result.URL != srv.URL+"/search/abc" {
+			t.Errorf("%s: URL = %q", tt.fixture, result.URL)
+		}
+	}
+}
+
+func TestSearchBlocked(t *testing.T) {
+	srv := pageServer(t, readFixture(t, "captcha.html"))
+	result, err := newTestClient(srv).Search(context.Background(), "abc")
+	if !errors.Is(err, ErrBlocked) || result.Found {
+		t.Errorf("Search = %+v, %v, want ErrBlocked", result, err)
+	}
+}
+
+func TestSearchUnrecognizedPage(t *testing.T) {
+	srv := pageServer(t, "<html><body>Welcome to the new Jotti</body></html>")
+	result, err := newTestClient(srv).Search(context.Background(), "abc")
+	if !errors.Is(err, ErrUnrecognizedPage) || result.Found {
+		t.Errorf("Search = %+v, %v, want ErrUnrecognizedPage", result, err)
+	}
+}
+
+func TestSearchHashInvalid(t *testing.T) {
+	c := NewClient()
+	c.SearchURL = "http://127.0.0.1:0/%s"
+	for _, hash := range []string{"", "xyz", strings.Repeat("a", 39), "../../etc"} {
+		if _, _, err := c.SearchHash(context.Background(), hash); !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("SearchHash(%q) err = %v, want ErrInvalidHash", hash, err)
+		}
+	}
+}
+
+func TestHashURL(t *testing.T) {
+	c := &Client{SearchURL: "https://jotti.example/search/%s"}
+	tests := map[string]string{
+		"abc":     "https://jotti.example/search/abc",
+		"a/b?c#d": "https://jotti.example/search/a%2Fb%3Fc%23d",
+		"..":      "https://jotti.example/search/%2E%2E",
+		".":       "https://jotti.example/search/%2E",
+	}
+	for hash, want := range tests {
+		if got := c.HashURL(hash); got != want {
+			t.Errorf("HashURL(%q) = %q, want %q", hash, got, want)
+		}
+	}
+}
+
+func TestRetryRateLimited(t *testing.T) {
+	rateLimitPage, resultsPage := readFixture(t, "ratelimit.html"), readFixture(t, "results.html")
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			// Jotti's rate limit page comes with 200 OK
+			io.WriteString(w, rateLimitPage)
+		default:
+			io.WriteString(w, resultsPage)
+		}
+	}))
+	defer srv.Close()
+
+	c := newTestClient(srv)
+	var logged []string
+	c.Logf = func(format string, v ...any) { logged = append(logged, format) }
+	result, err := c.Search(context.Background(), "abc")
+	if err != nil || !result.Found {
+		t.Fatalf("Search = %+v, %v, want found", result, err)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("server got %d requests, want 3", n)
+	}
+	if len(logged) != 2 {
+		t.Errorf("logged %d retries, want 2", len(logged))
+	}
+}
+
+func TestRetryExhausted(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	c := newTestClient(srv)
+	c.MaxRetries = 2
+	_, err := c.Search(context.Background(), "abc")
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("err = %v, want ErrRateLimited", err)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("server got %d requests, want 3", n)
+	}
+}
+
+func TestRetryCanceled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := newTestClient(srv).Search(ctx, "abc"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := map[string]time.Duration{
+		"":                              0,
+		"30":                            30 * time.Second,
+		"-5":                            0,
+		"soon":                          0,
+		"Mon, 01 Jan 2024 12:01:00 GMT": time.Minute,
+		"Mon, 01 Jan 2024 11:00:00 GMT": 0,
+	}
+	for value, want := range tests {
+		if got := parseRetryAfter(value, now); got != want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", value, got, want)
+		}
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		io.WriteString(w, "<html><script>var x;</script><p>Down for <b>repairs</b></p></html>")
+	}))
+	defer srv.Close()
+
+	_, err := newTestClient(srv).Search(context.Background(), "abc")
+	var se *HTTPStatusError
+	if !errors.As(err, &se) {
+		t.Fatalf("err = %v, want *HTTPStatusError", err)
+	}
+	if se.StatusCode != http.StatusServiceUnavailable || se.Body != "Down for repairs" {
+		t.Errorf("HTTPStatusError = %+v", se)
+	}
+}
+
+func TestRedirectNotFollowed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/login", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	c := newTestClient(srv)
+	c.HTTPClient.CheckRedirect = RedirectPolicy(false, nil)
+	_, err := c.Search(context.Background(), "abc")
+	var re *RedirectError
+	if !errors.Is(err, ErrRedirected) || !errors.As(err, &re) {
+		t.Fatalf("err = %v, want *RedirectError", err)
+	}
+	if re.Location != srv.URL+"/login" {
+		t.Errorf("Location = %q", re.Location)
+	}
+}
+
+func TestGzipBody(t *testing.T) {
+	page := readFixture(t, "results.html")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		io.WriteString(gz, page)
+		gz.Close()
+	}))
+	defer srv.Close()
+
+	// an Accept-Encoding set by hand turns off the transport's own decompression
+	c := newTestClient(srv)
+	c.Header = http.Header{"Accept-Encoding": {"gzip"}}
+	result, err := c.Search(context.Background(), "abc")
+	if err != nil || !result.Found {
+		t.Errorf("Search = %+v, %v, want found", result, err)
+	}
+}
+
+func TestWaitForResults(t *testing.T) {
+	pendingPage, resultsPage := readFixture(t, "pending.html"), readFixture(t, "results.html")
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			io.WriteString(w, "<p>Your file is queued</p>")
+		case 2:
+			io.WriteString(w, pendingPage)
+		default:
+			io.WriteString(w, resultsPage)
+		}
+	}))
+	defer srv.Close()
+
+	report, err := newTestClient(srv).WaitForResults(context.Background(), srv.URL+"/job", time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Pending || report.Detected != 2 || calls.Load() != 3 {
+		t.Errorf("report = %+v after %d requests", report, calls.Load())
+	}
+}
+
+func TestUploadStreamsWithContentLength(t *testing.T) {
+	files := map[string][]byte{
+		"a.bin": bytes.Repeat([]byte{0xAB}, 100_000),
+		"b.txt": []byte("hello"),
+	}
+	var paths []string
+	for name, data := range files {
+		paths = append(paths, writeTempFile(t, name, data))
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if len(r.TransferEncoding) != 0 || r.ContentLength != int64(len(body)) {
+			t.Errorf("Content-Length %d, Transfer-Encoding %v for a %d byte body", r.ContentLength, r.TransferEncoding, len(body))
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Error(err)
+			return
+		}
+		for _, fh := range r.MultipartForm.File[DefaultFormField] {
+			f, _ := fh.Open()
+			got, _ := io.ReadAll(f)
+			f.Close()
+			if !bytes.Equal(got, files[fh.Filename]) {
+				t.Errorf("part %s: %d bytes differ from the file", fh.Filename, len(got))
+			}
+		}
+		if n := len(r.MultipartForm.File[DefaultFormField]); n != len(files) {
+			t.Errorf("got %d parts, want %d", n, len(files))
+		}
+		io.WriteString(w, `<a href="/en-US/filescanjob/job42">results</a>`)
+	}))
+	defer srv.Close()
+
+	jobURL, err := newTestClient(srv).UploadBatch(context.Background(), paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := srv.URL + "/en-US/filescanjob/job42"; jobURL != want {
+		t.Errorf("job URL = %q, want %q", jobURL, want)
+	}
+}
+
+func TestUploadProgress(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 70_000)
+	path := writeTempFile(t, "p.bin", data)
+	srv := pageServer(t, "ok")
+
+	var last, calls int64
+	_, err := newTestClient(srv).UploadWithProgress(context.Background(), path, func(sent, total int64) {
+		if sent < last || total != int64(len(data)) {
+			t.Errorf("progress %d/%d after %d", sent, total, last)
+		}
+		last = sent
+		calls++
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last != int64(len(data)) || calls == 0 {
+		t.Errorf("progress ended at %d after %d calls, want %d", last, calls, len(data))
+	}
+}
+
+func TestUploadTooLarge(t *testing.T) {
+	defer func(old int64) { MaxUploadSize = old }(MaxUploadSize)
+	MaxUploadSize = 10
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer srv.Close()
+
+	path := writeTempFile(t, "big.bin", make([]byte, 11))
+	_, err := newTestClient(srv).Upload(context.Background(), path)
+	var tl *FileTooLargeError
+	if !errors.Is(err, ErrFileTooLarge) || !errors.As(err, &tl) {
+		t.Fatalf("err = %v, want *FileTooLargeError", err)
+	}
+	if tl.Size != 11 || tl.Max != 10 {
+		t.Errorf("FileTooLargeError = %+v", tl)
+	}
+	if calls.Load() != 0 {
+		t.Error("oversize file reached the server")
+	}
+}
+
+func TestMultipartLength(t *testing.T) {
+	names := []string{"a.exe", `we"ird name.zip`, "ünïcode.bin"}
+	sizes := []int{0, 1, 4096}
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	var total int64
+	for i, name := range names {
+		part, err := writer.CreateFormFile(DefaultFormField, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(make([]byte, sizes[i]))
+		total += int64(sizes[i])
+	}
+	writer.Close()
+
+	got, err := multipartLength(writer.Boundary(), DefaultFormField, names, total)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != int64(buf.Len()) {
+		t.Errorf("multipartLength = %d, want %d", got, buf.Len())
+	}
+}
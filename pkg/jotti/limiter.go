@@ -0,0 +1,129 @@
+package jotti
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket which can be shared by concurrent requests
+type Limiter struct {
+	mu       sync.Mutex
+	interval time.Duration // time to refill one token
+	burst    float64
+	tokens   float64
+	last     time.Time
+}
+
+// NewLimiter allows one request per interval with bursts of up to burst requests
+func NewLimiter(interval time.Duration, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		interval: interval,
+		burst:    float64(burst),
+		tokens:   float64(burst),
+		last:     time.Now(),
+	}
+}
+
+// Wait blocks until a token is available or ctx is done
+func (l *Limiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	if l.interval > 0 {
+		l.tokens += float64(now.Sub(l.last)) / float64(l.interval)
+	} else {
+		l.tokens = l.burst
+	}
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	// reserve a token, waiting for it if the bucket is empty
+	var wait time.Duration
+	if l.tokens < 1 {
+		wait = time.Duration((1 - l.tokens) * float64(l.interval))
+	}
+	l.tokens--
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	if err := sleepContext(ctx, wait); err != nil {
+		// hand back the unused token
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// ByteLimiter caps throughput in bytes per second, shared by concurrent uploads.
+// It covers what golang.org/x/time/rate would for uploads without another dependency.
+type ByteLimiter struct {
+	mu   sync.Mutex
+	rate float64   // bytes per second
+	next time.Time // when the bytes reserved so far have been paid for
+}
+
+// NewByteLimiter allows bytesPerSecond bytes per second
+func NewByteLimiter(bytesPerSecond int64) *ByteLimiter {
+	return &ByteLimiter{rate: float64(bytesPerSecond)}
+}
+
+// chunk size so a single read only blocks for about 50ms
+func (l *ByteLimiter) chunk() int {
+	return max(int(l.rate/20), 1024)
+}
+
+// WaitN blocks until n more bytes fit within the rate or ctx is done
+func (l *ByteLimiter) WaitN(ctx context.Context, n int) error {
+	l.mu.Lock()
+	now := time.Now()
+	// idle time doesn't build up credit for a burst
+	if l.next.Before(now) {
+		l.next = now
+	}
+	l.next = l.next.Add(time.Duration(float64(n) / l.rate * float64(time.Second)))
+	wait := l.next.Sub(now)
+	l.mu.Unlock()
+	return sleepContext(ctx, wait)
+}
+
+// reads from r no faster than the limiter allows
+type throttledReader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *ByteLimiter
+}
+
+func (t *throttledReader) Read(b []byte) (int, error) {
+	if len(b) > t.limiter.chunk() {
+		b = b[:t.limiter.chunk()]
+	}
+	n, err := t.r.Read(b)
+	if n > 0 {
+		if werr := t.limiter.WaitN(t.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+// sleep for d unless ctx is done first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,88 @@
+package jotti
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestLimiterBurst(t *testing.T) {
+	l := NewLimiter(time.Hour, 3)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d > 100*time.Millisecond {
+		t.Errorf("burst of 3 took %s", d)
+	}
+
+	// the bucket is empty, the next token is an hour away
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestLimiterPacing(t *testing.T) {
+	const interval = 20 * time.Millisecond
+	l := NewLimiter(interval, 1)
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the first request goes at once, the other three wait an interval each
+	if d := time.Since(start); d < 3*interval-5*time.Millisecond {
+		t.Errorf("4 requests took %s, want at least %s", d, 3*interval)
+	}
+}
+
+func TestLimiterCanceledWaitReturnsToken(t *testing.T) {
+	l := NewLimiter(50*time.Millisecond, 1)
+	l.Wait(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	// the canceled waiter must not push the next one back another interval
+	start := time.Now()
+	l.Wait(context.Background())
+	if d := time.Since(start); d > 80*time.Millisecond {
+		t.Errorf("wait after a canceled one took %s", d)
+	}
+}
+
+func TestLimiterZeroInterval(t *testing.T) {
+	l := NewLimiter(0, 0)
+	for i := 0; i < 100; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestThrottledReader(t *testing.T) {
+	data := bytes.Repeat([]byte("z"), 20_000)
+	r := &throttledReader{ctx: context.Background(), r: bytes.NewReader(data), limiter: NewByteLimiter(100_000)}
+	start := time.Now()
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("throttled read changed the data")
+	}
+	// 20KB at 100KB/s
+	if d := time.Since(start); d < 150*time.Millisecond {
+		t.Errorf("read took %s, want about 200ms", d)
+	}
+}
@@ -0,0 +1,157 @@
+package jotti
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// ProgressFunc is called as an upload is sent with the bytes sent so far and the
+// total, from the goroutine writing the request body
+type ProgressFunc func(sent, total int64)
+
+// counts bytes read from r for a ProgressFunc, r is swapped per file during batch uploads
+type progressReader struct {
+	r     io.Reader
+	sent  int64
+	total int64
+	fn    ProgressFunc
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.sent += int64(n)
+		p.fn(p.sent, p.total)
+	}
+	return n, err
+}
+
+// terminal progress bar, Client.Progress uses its update as the ProgressFunc
+type progressBar struct {
+	w        io.Writer
+	total    int64
+	read     int64
+	lastTick time.Time
+	lastRead int64   // bytes read at lastTick
+	rate     float64 // smoothed transfer rate in bytes/s
+	lastLen  int     // length of the previous line, for clearing
+	color    bool    // draw the filled bar in green
+}
+
+// bar cells when the terminal width is unknown, e.g. output is redirected
+const progressBarWidth = 20
+
+// columns kept for the text around the bar, an 80 column terminal gets the default 20 cells
+const (
+	progressLineReserve = 60
+	progressBarMinWidth = 10
+	progressBarMaxWidth = 100
+)
+
+// weight of the newest sample in the rolling rate
+const rateSmoothing = 0.3
+
+func (p *progressBar) update(sent, total int64) {
+	p.read, p.total = sent, total
+	if p.read >= p.total {
+		p.renderDone()
+		return
+	}
+	now := time.Now()
+	if dt := now.Sub(p.lastTick); dt >= 150*time.Millisecond {
+		if !p.lastTick.IsZero() && dt > 0 {
+			p.updateRate(float64(p.read-p.lastRead) / dt.Seconds())
+		}
+		p.render()
+		p.lastTick = now
+		p.lastRead = p.read
+	}
+}
+
+func (p *progressBar) updateRate(sample float64) {
+	if p.rate == 0 {
+		p.rate = sample
+		return
+	}
+	p.rate = rateSmoothing*sample + (1-rateSmoothing)*p.rate
+}
+
+// estimated time to send the remaining bytes, 0 if unknown
+func (p *progressBar) eta() time.Duration {
+	if p.rate <= 0 || p.read >= p.total {
+		return 0
+	}
+	secs := float64(p.total-p.read) / p.rate
+	return time.Duration(secs * float64(time.Second)).Round(time.Second)
+}
+
+// bar cells fitted to the terminal, checked on each render so resizes are picked up
+func (p *progressBar) width() int {
+	f, ok := p.w.(interface{ Fd() uintptr })
+	if !ok {
+		return progressBarWidth
+	}
+	cols, _, err := term.GetSize(int(f.Fd()))
+	if err != nil || cols <= 0 {
+		return progressBarWidth
+	}
+	return min(max(cols-progressLineReserve, progressBarMinWidth), progressBarMaxWidth)
+}
+
+func (p *progressBar) render() {
+	width := p.width()
+	percent := float64(p.read) * 100 / float64(p.total)
+	filled := barFill(p.read, p.total, width)
+	line := fmt.Sprintf("Progress: [%s] %6.2f%% %s/%s", p.bar(filled, width), percent, formatMB(float64(p.read)), formatMB(float64(p.total)))
+	if p.rate > 0 {
+		line += fmt.Sprintf(" %s/s", formatMB(p.rate))
+		if eta := p.eta(); eta > 0 {
+			line += fmt.Sprintf(" ETA %s", eta)
+		}
+	}
+	p.print(line)
+}
+
+func (p *progressBar) renderDone() {
+	width := p.width()
+	p.print(fmt.Sprintf("Progress: [%s] 100.00%% (sent) - waiting response...", p.bar(width, width)))
+}
+
+// cells of a width wide bar filled after sent of total bytes, exact for any width
+// and clamped when a file grows while it is sent
+func barFill(sent, total int64, width int) int {
+	if total <= 0 || sent <= 0 {
+		return 0
+	}
+	if sent >= total {
+		return width
+	}
+	return int(sent * int64(width) / total)
+}
+
+// bar text of width cells, the first filled ones colored when enabled
+func (p *progressBar) bar(filled, width int) string {
+	done, rest := strings.Repeat("=", filled), strings.Repeat(" ", width-filled)
+	if !p.color || filled == 0 {
+		return done + rest
+	}
+	return "\033[32m" + done + "\033[0m" + rest
+}
+
+// overwrite the current line, padding over leftovers of a longer previous one
+func (p *progressBar) print(line string) {
+	pad := ""
+	if n := p.lastLen - len(line); n > 0 {
+		pad = strings.Repeat(" ", n) + strings.Repeat("\b", n)
+	}
+	fmt.Fprintf(p.w, "\r%s%s", line, pad)
+	p.lastLen = len(line)
+}
+
+func formatMB(bytes float64) string {
+	return fmt.Sprintf("%.2f MB", bytes/(1024*1024))
+}
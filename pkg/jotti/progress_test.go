@@ -0,0 +1,82 @@
+package jotti
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestBarFill(t *testing.T) {
+	tests := []struct {
+		sent, total int64
+		width, want int
+	}{
+		{0, 100, 20, 0},
+		{-1, 100, 20, 0},
+		{50, 100, 20, 10},
+		{99, 100, 20, 19},
+		{100, 100, 20, 20},
+		{150, 100, 20, 20}, // file grew while it was sent
+		{10, 0, 20, 0},
+		{1, 3, 100, 33},
+		// sizes far above Jotti's limit
+		{1 << 40, 1 << 41, 100, 50},
+	}
+	for _, tt := range tests {
+		if got := barFill(tt.sent, tt.total, tt.width); got != tt.want {
+			t.Errorf("barFill(%d, %d, %d) = %d, want %d", tt.sent, tt.total, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestProgressReaderPartialReads(t *testing.T) {
+	data := strings.Repeat("p", 1000)
+	var updates []int64
+	pr := &progressReader{
+		r:     iotest.HalfReader(strings.NewReader(data)),
+		total: int64(len(data)),
+		fn:    func(sent, total int64) { updates = append(updates, sent) },
+	}
+	got, err := io.ReadAll(pr)
+	if err != nil || string(got) != data {
+		t.Fatalf("ReadAll = %d bytes, %v", len(got), err)
+	}
+	for i := 1; i < len(updates); i++ {
+		if updates[i] <= updates[i-1] {
+			t.Fatalf("progress went from %d to %d", updates[i-1], updates[i])
+		}
+	}
+	if updates[len(updates)-1] != int64(len(data)) {
+		t.Errorf("progress ended at %d, want %d", updates[len(updates)-1], len(data))
+	}
+}
+
+func TestProgressReaderDataWithError(t *testing.T) {
+	// a reader returning data together with io.EOF must still count it
+	var sent int64
+	pr := &progressReader{
+		r:     iotest.DataErrReader(bytes.NewReader([]byte("12345"))),
+		total: 5,
+		fn:    func(s, total int64) { sent = s },
+	}
+	io.ReadAll(pr)
+	if sent != 5 {
+		t.Errorf("sent = %d, want 5", sent)
+	}
+}
+
+func TestProgressBarRender(t *testing.T) {
+	var buf bytes.Buffer
+	bar := &progressBar{w: &buf}
+	bar.update(10, 100)
+	if !strings.Contains(buf.String(), "[==                  ]  10.00%") {
+		t.Errorf("bar = %q", buf.String())
+	}
+	buf.Reset()
+	bar.update(100, 100)
+	if !strings.Contains(buf.String(), "100.00% (sent)") {
+		t.Errorf("finished bar = %q", buf.String())
+	}
+}
@@ -0,0 +1,230 @@
+package jotti
+
+import (
+	"errors"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// EngineResult is the verdict of a single scan engine
+type EngineResult struct {
+	Scanner  string `json:"scanner"`
+	Result   string `json:"result"`
+	Detected bool   `json:"detected"`
+	Pending  bool   `json:"pending,omitempty"` // engine has not finished scanning yet
+}
+
+// ErrNoScanResults is returned when a page contains no engine verdicts
+var ErrNoScanResults = errors.New("no scan results found in page")
+
+var (
+	rowRegex      = regexp.MustCompile(`(?is)<tr[^>]*>(.*?)</tr>`)
+	cellRegex     = regexp.MustCompile(`(?is)<td([^>]*)>(.*?)</td>`)
+	imgAltRegex   = regexp.MustCompile(`(?i)<img[^>]*\balt="([^"]*)"`)
+	tagRegex      = regexp.MustCompile(`(?s)<[^>]*>`)
+	scanDateRegex = regexp.MustCompile(`(?i)scan(?:ned)?(?:\s+date)?\s*(?:on)?\s*:?\s*(?:<[^>]*>\s*)*(\d{4}-\d{2}-\d{2}[ T]\d{2}:\d{2}(?::\d{2})?)`)
+	scanJobRegex  = regexp.MustCompile(`(?i)["']((?:https?://[^"'\s]*)?/[^"'\s]*filescanjob/[A-Za-z0-9_-]+)["']`)
+	scriptRegex   = regexp.MustCompile(`(?is)<(script|style)[^>]*>.*?</(script|style)>`)
+	spaceRegex    = regexp.MustCompile(`\s+`)
+	cleanVerdicts = []string{"", "-", "found nothing", "clean", "not detected", "no threat found"}
+	// verdicts shown while a fresh upload is still being scanned
+	pendingVerdicts = []string{"scanning", "queued", "waiting", "in progress", "pending"}
+)
+
+// ErrUnrecognizedPage is returned when a search page is neither a results nor a not-found page
+var ErrUnrecognizedPage = errors.New("unrecognized Jotti page")
+
+// PageKind is what a Jotti search page shows
+type PageKind int
+
+const (
+	PageUnknown  PageKind = iota // layout changed or unexpected content
+	PageNotFound                 // hash is not known to Jotti
+	PageResults                  // engine verdicts for a known hash
+)
+
+var (
+	// markup hooks of the not-found page come first so wording and locale changes still match
+	notFoundMarkers = []string{`class="notfound"`, `id="notfound"`, "hash-not-found", "hash not found"}
+	// text of Jotti's rate limit page, served with 200 OK
+	rateLimitMarkers = []string{"too many requests"}
+)
+
+// ClassifyPage reports whether body is a results page, a not-found page or neither
+func ClassifyPage(body string) PageKind {
+	if containsAny(body, notFoundMarkers) {
+		return PageNotFound
+	}
+	if _, err := ParseScanResults(body); err == nil {
+		return PageResults
+	}
+	return PageUnknown
+}
+
+func isRateLimitPage(body string) bool {
+	return containsAny(body, rateLimitMarkers)
+}
+
+// case-insensitive substring match against any marker
+func containsAny(body string, markers []string) bool {
+	lower := strings.ToLower(body)
+	for _, m := range markers {
+		if strings.Contains(lower, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseScanResults extracts per-engine verdicts from a Jotti results page
+func ParseScanResults(body string) ([]EngineResult, error) {
+	var results []EngineResult
+	for _, row := range rowRegex.FindAllStringSubmatch(body, -1) {
+		cells := cellRegex.FindAllStringSubmatch(row[1], -1)
+		if len(cells) < 2 {
+			continue
+		}
+
+		scanner := htmlText(cells[0][2])
+		if scanner == "" {
+			if m := imgAltRegex.FindStringSubmatch(cells[0][2]); m != nil {
+				scanner = html.UnescapeString(m[1])
+			}
+		}
+		if scanner == "" {
+			continue
+		}
+
+		// prefer the cell marked as result, fall back to the second cell
+		result := htmlText(cells[1][2])
+		for _, c := range cells[1:] {
+			if strings.Contains(strings.ToLower(c[1]), "result") {
+				result = htmlText(c[2])
+				break
+			}
+		}
+
+		pending := isPendingVerdict(result)
+		results = append(results, EngineResult{
+			Scanner:  scanner,
+			Result:   result,
+			Detected: !pending && !isCleanVerdict(result),
+			Pending:  pending,
+		})
+	}
+
+	if len(results) == 0 {
+		return nil, ErrNoScanResults
+	}
+	return results, nil
+}
+
+// Report is the outcome of a scan as shown on its results page
+type Report struct {
+	URL       string         `json:"url"`
+	ScanDate  time.Time      `json:"scan_date,omitzero"`  // zero when the page shows none
+	Permalink string         `json:"permalink,omitempty"` // link to the latest scan job, "" when the page has none
+	Engines   []EngineResult `json:"engines"`
+	Detected  int            `json:"detected"` // engines which detected something
+	Pending   bool           `json:"pending"`  // some engines had not finished scanning
+}
+
+// ParseScanDate finds the "Scan date: 2006-01-02 15:04:05" line of a results page,
+// Jotti shows UTC times
+func ParseScanDate(body string) (time.Time, bool) {
+	m := scanDateRegex.FindStringSubmatch(body)
+	if m == nil {
+		return time.Time{}, false
+	}
+	value := strings.Replace(m[1], "T", " ", 1)
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ParsePermalink returns the absolute link to the first scan job on a results page,
+// Jotti lists the latest scan first, "" when there is none
+func ParsePermalink(pageURL, body string) string {
+	m := scanJobRegex.FindStringSubmatch(body)
+	if m == nil {
+		return ""
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+	u, err := base.Parse(html.UnescapeString(m[1]))
+	if err != nil {
+		return ""
+	}
+	return u.String()
+}
+
+// ParseReport parses the verdicts and scan date of a results page fetched from pageURL
+func ParseReport(pageURL, body string) (Report, error) {
+	report := Report{URL: pageURL}
+	report.ScanDate, _ = ParseScanDate(body)
+	report.Permalink = ParsePermalink(pageURL, body)
+	engines, err := ParseScanResults(body)
+	if err != nil {
+		return report, err
+	}
+	report.Engines = engines
+	report.Detected = CountDetected(engines)
+	report.Pending = ScanPending(engines)
+	return report, nil
+}
+
+// CountDetected returns the number of engines which detected something
+func CountDetected(engines []EngineResult) int {
+	n := 0
+	for _, e := range engines {
+		if e.Detected {
+			n++
+		}
+	}
+	return n
+}
+
+// ScanPending reports whether any engine is still scanning
+func ScanPending(engines []EngineResult) bool {
+	for _, e := range engines {
+		if e.Pending {
+			return true
+		}
+	}
+	return false
+}
+
+// strip markup and collapse whitespace
+func htmlText(s string) string {
+	s = tagRegex.ReplaceAllString(s, " ")
+	s = html.UnescapeString(s)
+	return strings.TrimSpace(spaceRegex.ReplaceAllString(s, " "))
+}
+
+func isCleanVerdict(result string) bool {
+	result = strings.ToLower(result)
+	for _, v := range cleanVerdicts {
+		if result == v {
+			return true
+		}
+	}
+	return false
+}
+
+func isPendingVerdict(result string) bool {
+	result = strings.ToLower(strings.TrimRight(result, ". "))
+	for _, v := range pendingVerdicts {
+		if strings.HasPrefix(result, v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package jotti
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func readFixture(t testing.TB, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestParseScanResults(t *testing.T) {
+	engines, err := ParseScanResults(readFixture(t, "results.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []EngineResult{
+		{Scanner: "Avast", Result: "Found nothing"},
+		{Scanner: "Bitdefender", Result: "Trojan.GenericKD.31", Detected: true},
+		{Scanner: "ClamAV", Result: "-"},
+		{Scanner: "ESET", Result: "Win32/Agent.XYZ", Detected: true},
+	}
+	if len(engines) != len(want) {
+		t.Fatalf("got %d engines, want %d: %+v", len(engines), len(want), engines)
+	}
+	for i := range want {
+		if engines[i] != want[i] {
+			t.Errorf("engine %d = %+v, want %+v", i, engines[i], want[i])
+		}
+	}
+}
+
+func TestParseScanResultsPending(t *testing.T) {
+	engines, err := ParseScanResults(readFixture(t, "pending.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !engines[0].Pending || engines[0].Detected {
+		t.Errorf("scanning engine = %+v, want pending and not detected", engines[0])
+	}
+	if !ScanPending(engines) {
+		t.Error("ScanPending = false, want true")
+	}
+}
+
+func TestParseScanResultsEmpty(t *testing.T) {
+	if _, err := ParseScanResults(readFixture(t, "notfound.html")); !errors.Is(err, ErrNoScanResults) {
+		t.Errorf("err = %v, want ErrNoScanResults", err)
+	}
+}
+
+func TestClassifyPage(t *testing.T) {
+	tests := []struct {
+		body string
+		want PageKind
+	}{
+		{readFixture(t, "results.html"), PageResults},
+		{readFixture(t, "pending.html"), PageResults},
+		{readFixture(t, "notfound.html"), PageNotFound},
+		{`<p id="notfound">Hash niet gevonden</p>`, PageNotFound},
+		{"<html><body>Hash not found</body></html>", PageNotFound},
+		{"<html><body>Welcome to the new Jotti</body></html>", PageUnknown},
+		{"", PageUnknown},
+	}
+	for i, tt := range tests {
+		if got := ClassifyPage(tt.body); got != tt.want {
+			t.Errorf("case %d: ClassifyPage = %d, want %d", i, got, tt.want)
+		}
+	}
+}
+
+func TestParseScanDate(t *testing.T) {
+	tests := []struct {
+		body string
+		want time.Time
+		ok   bool
+	}{
+		{readFixture(t, "results.html"), time.Date(2024, 3, 5, 14, 7, 9, 0, time.UTC), true},
+		{"Scanned on 2023-12-31T23:59", time.Date(2023, 12, 31, 23, 59, 0, 0, time.UTC), true},
+		{"no date here", time.Time{}, false},
+	}
+	for i, tt := range tests {
+		got, ok := ParseScanDate(tt.body)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("case %d: ParseScanDate = %v, %v, want %v, %v", i, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParsePermalink(t *testing.T) {
+	body := readFixture(t, "results.html")
+	got := ParsePermalink("https://virusscan.jotti.org/en-US/search/hash/abc", body)
+	if want := "https://virusscan.jotti.org/en-US/filescanjob/a1B2c3_d4"; got != want {
+		t.Errorf("ParsePermalink = %q, want %q", got, want)
+	}
+	if got := ParsePermalink("https://virusscan.jotti.org/", "<p>none</p>"); got != "" {
+		t.Errorf("ParsePermalink without a link = %q, want empty", got)
+	}
+}
+
+func TestParseReport(t *testing.T) {
+	pageURL := "https://virusscan.jotti.org/en-US/search/hash/abc"
+	report, err := ParseReport(pageURL, readFixture(t, "results.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.URL != pageURL || report.Detected != 2 || report.Pending || len(report.Engines) != 4 {
+		t.Errorf("report = %+v", report)
+	}
+	if report.ScanDate.IsZero() || report.Permalink == "" {
+		t.Errorf("report lacks scan date or permalink: %+v", report)
+	}
+
+	report, err = ParseReport(pageURL, readFixture(t, "notfound.html"))
+	if !errors.Is(err, ErrNoScanResults) || report.URL != pageURL {
+		t.Errorf("not-found page: report = %+v, err = %v", report, err)
+	}
+}
@@ -0,0 +1,97 @@
+package jotti
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// LoggingTransport logs each request URL, response status, content length and timing
+type LoggingTransport struct {
+	Transport http.RoundTripper // nil uses http.DefaultTransport
+	Logf      func(format string, v ...any)
+	Headers   bool // also log response headers
+}
+
+func (t *LoggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	t.Logf("> %s %s\n", req.Method, req.URL)
+	start := time.Now()
+	resp, err := transport.RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		t.Logf("< %s %s failed after %s: %v\n", req.Method, req.URL, elapsed, err)
+		return nil, err
+	}
+
+	t.Logf("< %s %s in %s, content-length %d\n", resp.Status, req.URL, elapsed, resp.ContentLength)
+	if t.Headers {
+		keys := make([]string, 0, len(resp.Header))
+		for k := range resp.Header {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			for _, v := range resp.Header[k] {
+				t.Logf("<   %s: %s\n", k, v)
+			}
+		}
+	}
+	return resp, nil
+}
+
+// RedirectError is returned for a redirect that wasn't followed, it matches ErrRedirected
+type RedirectError struct {
+	StatusCode int
+	URL        string // requested page
+	Location   string // where Jotti sent it, e.g. a login or CAPTCHA page
+}
+
+func (e *RedirectError) Error() string {
+	return fmt.Sprintf("%v: %d from %s to %s", ErrRedirected, e.StatusCode, e.URL, e.Location)
+}
+
+func (e *RedirectError) Is(target error) bool {
+	return target == ErrRedirected
+}
+
+func isRedirect(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+func redirectError(response *http.Response) error {
+	location := response.Header.Get("Location")
+	if u, err := response.Request.URL.Parse(location); err == nil {
+		location = u.String()
+	}
+	return &RedirectError{StatusCode: response.StatusCode, URL: response.Request.URL.String(), Location: location}
+}
+
+// RedirectPolicy is a CheckRedirect for Client.HTTPClient. Each hop is passed to
+// logf when set. Without follow the first redirect is returned as a *RedirectError
+// (uploads treat it as the scan job link).
+func RedirectPolicy(follow bool, logf func(format string, v ...any)) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if logf != nil {
+			logf("Redirect %s -> %s\n", via[len(via)-1].URL, req.URL)
+		}
+		if !follow {
+			return http.ErrUseLastResponse
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cyclone-github/jotti/pkg/jotti"
+)
+
+// writes per-file results to stdout or the -output file
+type reporter interface {
+	write(r scanResult) error
+	close() error
+}
+
+// open report destination, stdout when path is empty
+func openReport(path string, appendMode bool) (io.WriteCloser, error) {
+	if path == "" {
+		return nopCloser{stdout}, nil
+	}
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if appendMode {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	return os.OpenFile(path, flags, 0o644)
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+// newline-delimited JSON, one object per file
+type jsonReporter struct {
+	enc *json.Encoder
+	wc  io.WriteCloser
+}
+
+func newJSONReporter(wc io.WriteCloser) *jsonReporter {
+	return &jsonReporter{enc: json.NewEncoder(wc), wc: wc}
+}
+
+func (j *jsonReporter) write(r scanResult) error { return j.enc.Encode(r) }
+func (j *jsonReporter) close() error             { return j.wc.Close() }
+
+// run totals, the last object of a -json report, told apart from file objects by its one key
+type jsonSummary struct {
+	Summary struct {
+		Scanned       int     `json:"scanned"`
+		Found         int     `json:"found"`
+		Uploaded      int     `json:"uploaded"`
+		Detected      int     `json:"detected"`
+		Errors        int     `json:"errors"`
+		Bytes         int64   `json:"bytes"`
+		UploadedBytes int64   `json:"uploaded_bytes"`
+		Elapsed       float64 `json:"elapsed"`
+	} `json:"summary"`
+}
+
+func (j *jsonReporter) summary(s *runStats, elapsed time.Duration) error {
+	var sum jsonSummary
+	sum.Summary.Scanned, sum.Summary.Found, sum.Summary.Uploaded = s.scanned, s.found, s.uploaded
+	sum.Summary.Detected, sum.Summary.Errors = s.detected, s.errors
+	sum.Summary.Bytes, sum.Summary.UploadedBytes = s.bytes, s.uploadedBytes
+	sum.Summary.Elapsed = elapsed.Seconds()
+	return j.enc.Encode(sum)
+}
+
+// CSV with a header row, flushed on close
+type csvReporter struct {
+	cw       *csv.Writer
+	wc       io.WriteCloser
+	hashOnly bool // checksum columns instead of search results
+}
+
+func newCSVReporter(wc io.WriteCloser, hashOnly bool) *csvReporter {
+	cw := csv.NewWriter(wc)
+	if hashOnly {
+		cw.Write([]string{"file", "md5", "sha1", "sha256", "error"})
+	} else {
+		cw.Write([]string{"file", "sha1", "found", "url", "error"})
+	}
+	return &csvReporter{cw: cw, wc: wc, hashOnly: hashOnly}
+}
+
+func (c *csvReporter) write(r scanResult) error {
+	errMsg := ""
+	if r.Error != nil {
+		errMsg = *r.Error
+	}
+	if c.hashOnly {
+		return c.cw.Write([]string{r.File, r.MD5, r.SHA1, r.SHA256, errMsg})
+	}
+	return c.cw.Write([]string{r.File, r.SHA1, strconv.FormatBool(r.Found), r.JottiURL, errMsg})
+}
+
+func (c *csvReporter) close() error {
+	c.cw.Flush()
+	if err := c.cw.Error(); err != nil {
+		c.wc.Close()
+		return err
+	}
+	return c.wc.Close()
+}
+
+// plain-text table, flushed on close
+type textReporter struct {
+	tw *tabwriter.Writer
+	wc io.WriteCloser
+}
+
+func newTextReporter(wc io.WriteCloser) *textReporter {
+	tw := tabwriter.NewWriter(wc, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tSHA1\tFOUND\tUPLOADED\tURL\tERROR")
+	return &textReporter{tw: tw, wc: wc}
+}
+
+func (t *textReporter) write(r scanResult) error {
+	errMsg := ""
+	if r.Error != nil {
+		errMsg = *r.Error
+	}
+	_, err := fmt.Fprintf(t.tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+		r.File, r.SHA1, strconv.FormatBool(r.Found), strconv.FormatBool(r.Uploaded), r.JottiURL, errMsg)
+	return err
+}
+
+func (t *textReporter) close() error {
+	if err := t.tw.Flush(); err != nil {
+		t.wc.Close()
+		return err
+	}
+	return t.wc.Close()
+}
+
+// one-word outcome of a scan for summaries
+func (r scanResult) status() string {
+	switch {
+	case r.Error != nil:
+		return "ERROR"
+	case r.Found:
+		return "FOUND"
+	case r.Uploaded:
+		return "UPLOADED"
+	case r.Duplicate != "":
+		return "DUPLICATE"
+	case r.HashOnly:
+		return "HASHED"
+	default:
+		return "SKIPPED"
+	}
+}
+
+// upload volume of the run, for bandwidth accounting
+func printUploadTotals(w io.Writer, s *runStats, elapsed time.Duration) {
+	rate := 0.0
+	if elapsed > 0 {
+		rate = float64(s.uploadedBytes) / elapsed.Seconds()
+	}
+	d := elapsed.Round(time.Second)
+	if d == 0 {
+		d = elapsed.Round(time.Millisecond)
+	}
+	files := "files"
+	if s.uploaded == 1 {
+		files = "file"
+	}
+	fmt.Fprintf(w, "Uploaded %d %s, %s in %s (avg %s/s)\n", s.uploaded, files, formatBytes(float64(s.uploadedBytes)), d, formatBytes(rate))
+}
+
+// 142.5 MB, binary units like parseSize
+func formatBytes(n float64) string {
+	for _, u := range []struct {
+		name string
+		size float64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}} {
+		if n >= u.size {
+			return fmt.Sprintf("%.1f %s", n/u.size, u.name)
+		}
+	}
+	return fmt.Sprintf("%.0f B", n)
+}
+
+// per-engine breakdown of one file for -results-format json
+type engineReport struct {
+	File     string               `json:"file"`
+	URL      string               `json:"url,omitempty"`
+	ScanDate *time.Time           `json:"scan_date,omitempty"`
+	Detected int                  `json:"detected"`
+	Total    int                  `json:"total"`
+	Ratio    string               `json:"ratio"`
+	Engines  []jotti.EngineResult `json:"engines"`
+}
+
+func newEngineReport(r scanResult) engineReport {
+	return engineReport{
+		File:     r.File,
+		URL:      r.JottiURL,
+		ScanDate: r.ScanDate,
+		Detected: r.Detected,
+		Total:    len(r.Engines),
+		Ratio:    fmt.Sprintf("%d/%d", r.Detected, len(r.Engines)),
+		Engines:  r.Engines,
+	}
+}
+
+// aligned end-of-run table of every file followed by totals
+func printSummary(w io.Writer, s *runStats, color bool) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "\nFILE\tSHA1\t%s\tDETECTED\n", statusLabel("STATUS", color))
+	for _, r := range s.results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\n", r.File, r.SHA1, statusLabel(r.status(), color), r.Detected)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "Files scanned: %d, found: %d, uploaded: %d, errors: %d\n", s.scanned, s.found, s.uploaded, s.errors)
+}
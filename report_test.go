@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cyclone-github/jotti/pkg/jotti"
+)
+
+// scan result filled from the results page fixture of pkg/jotti, text verdicts go to w
+func fixtureResult(t *testing.T, w *bytes.Buffer) scanResult {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("pkg", "jotti", "testdata", "results.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pageURL := "https://virusscan.jotti.org/en-US/search/hash/abc"
+	report, err := jotti.ParseReport(pageURL, string(body))
+	r := scanResult{File: "sample.exe", JottiURL: pageURL}
+	r.setReport(w, report, err)
+	return r
+}
+
+func TestEngineReportText(t *testing.T) {
+	var buf bytes.Buffer
+	fixtureResult(t, &buf)
+	want := "DETECTED 2/4 engines\n" +
+		"  Bitdefender: Trojan.GenericKD.31\n" +
+		"  ESET: Win32/Agent.XYZ\n"
+	if buf.String() != want {
+		t.Errorf("text verdicts =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestEngineReportJSON(t *testing.T) {
+	var buf bytes.Buffer
+	r := fixtureResult(t, &buf)
+	data, err := json.Marshal(newEngineReport(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"file":"sample.exe","url":"https://virusscan.jotti.org/en-US/search/hash/abc",` +
+		`"scan_date":"2024-03-05T14:07:09Z","detected":2,"total":4,"ratio":"2/4","engines":[` +
+		`{"scanner":"Avast","result":"Found nothing","detected":false},` +
+		`{"scanner":"Bitdefender","result":"Trojan.GenericKD.31","detected":true},` +
+		`{"scanner":"ClamAV","result":"-","detected":false},` +
+		`{"scanner":"ESET","result":"Win32/Agent.XYZ","detected":true}]}`
+	if string(data) != want {
+		t.Errorf("json =\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestPrintUploadTotals(t *testing.T) {
+	tests := []struct {
+		uploaded int
+		want     string
+	}{
+		{1, "Uploaded 1 file, 2.0 KB in 2s (avg 1.0 KB/s)\n"},
+		{3, "Uploaded 3 files, 2.0 KB in 2s (avg 1.0 KB/s)\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		printUploadTotals(&buf, &runStats{uploaded: tt.uploaded, uploadedBytes: 2048}, 2*time.Second)
+		if buf.String() != tt.want {
+			t.Errorf("%d uploads: %q, want %q", tt.uploaded, buf.String(), tt.want)
+		}
+	}
+}
+
+func TestJSONReporterSummary(t *testing.T) {
+	var buf bytes.Buffer
+	rep := newJSONReporter(nopCloser{&buf})
+	if err := rep.write(scanResult{File: "a.exe", Uploaded: true}); err != nil {
+		t.Fatal(err)
+	}
+	s := &runStats{scanned: 1, uploaded: 1, bytes: 10, uploadedBytes: 10}
+	if err := rep.summary(s, 1500*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	want := `{"summary":{"scanned":1,"found":0,"uploaded":1,"detected":0,"errors":0,"bytes":10,"uploaded_bytes":10,"elapsed":1.5}}`
+	if len(lines) != 2 || lines[1] != want {
+		t.Errorf("report =\n%s\nwant the summary %s last", buf.String(), want)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+)
+
+// temp directory for files jotti creates itself, made on first use
+type scratchDir struct {
+	dir string
+}
+
+// new empty directory inside the scratch directory
+func (s *scratchDir) mkdir(name string) (string, error) {
+	if s.dir == "" {
+		dir, err := os.MkdirTemp("", "jotti-")
+		if err != nil {
+			return "", err
+		}
+		s.dir = dir
+	}
+	return os.MkdirTemp(s.dir, name+"-")
+}
+
+// remove everything created in this run
+func (s *scratchDir) cleanup() {
+	if s.dir == "" {
+		return
+	}
+	if err := os.RemoveAll(s.dir); err != nil {
+		slog.Error("Error removing", "file", s.dir, "error", err)
+	}
+}
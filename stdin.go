@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/cyclone-github/jotti/pkg/jotti"
+)
+
+// "-" as an argument scans a sample piped on stdin
+const stdinArg = "-"
+
+// copy r into the scratch directory so it can be hashed and size checked like any file
+func (s *scratchDir) readStdin(r io.Reader) (string, error) {
+	dir, err := s.mkdir("stdin")
+	if err != nil {
+		return "", err
+	}
+	// the file name becomes the multipart name, so keep it readable
+	target := filepath.Join(dir, "stdin")
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return "", err
+	}
+	// the size isn't known up front, stop reading once past the cap
+	n, err := io.Copy(out, io.LimitReader(r, jotti.MaxUploadSize+1))
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && n > jotti.MaxUploadSize {
+		err = fmt.Errorf("%w: stdin exceeds %d byte limit", jotti.ErrFileTooLarge, jotti.MaxUploadSize)
+	}
+	if err != nil {
+		os.Remove(target)
+		return "", err
+	}
+	return target, nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// time spent per stage of a file, recorded with -timings
+type stageTimings struct {
+	hash, search, upload, results time.Duration
+}
+
+func (t *stageTimings) add(o stageTimings) {
+	t.hash += o.hash
+	t.search += o.search
+	t.upload += o.upload
+	t.results += o.results
+}
+
+func (t stageTimings) String() string {
+	r := func(d time.Duration) time.Duration { return d.Round(time.Millisecond) }
+	return fmt.Sprintf("hash %s, search %s, upload %s, results %s", r(t.hash), r(t.search), r(t.upload), r(t.results))
+}
+
+// add time since start to a stage
+func since(stage *time.Duration, start time.Time) {
+	*stage += time.Since(start)
+}
+
+func printTimings(w io.Writer, total stageTimings, files int) {
+	fmt.Fprintf(w, "Timings for %d files: %s\n", files, total)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+)
+
+// VirusTotal v3 file report endpoint, %s is replaced by the hash
+const vtReportURL = "https://www.virustotal.com/api/v3/files/%s"
+
+// API key from -vt-key, empty disables the VirusTotal fallback
+var vtKey string
+
+// VirusTotal verdict for a hash not found on Jotti
+type vtResult struct {
+	Found      bool   `json:"found"`
+	URL        string `json:"url"`
+	Malicious  int    `json:"malicious"`
+	Suspicious int    `json:"suspicious"`
+	Engines    int    `json:"engines"`
+}
+
+// query VirusTotal's hash report, found is false for unknown hashes
+func vtLookup(ctx context.Context, hash string) (*vtResult, error) {
+	request, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf(vtReportURL, url.PathEscape(hash)), nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("x-apikey", vtKey)
+	request.Header.Set("Accept", "application/json")
+
+	response, err := client.HTTPClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	result := &vtResult{URL: "https://www.virustotal.com/gui/file/" + hash}
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return result, nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, fmt.Errorf("VirusTotal rejected the API key (status %d)", response.StatusCode)
+	case http.StatusTooManyRequests:
+		return nil, fmt.Errorf("VirusTotal API quota exceeded")
+	default:
+		return nil, fmt.Errorf("VirusTotal returned status %d", response.StatusCode)
+	}
+
+	var report struct {
+		Data struct {
+			Attributes struct {
+				Stats map[string]int `json:"last_analysis_stats"`
+			} `json:"attributes"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(io.LimitReader(response.Body, 10<<20)).Decode(&report); err != nil {
+		return nil, fmt.Errorf("decoding VirusTotal report: %w", err)
+	}
+	stats := report.Data.Attributes.Stats
+	result.Found = true
+	result.Malicious = stats["malicious"]
+	result.Suspicious = stats["suspicious"]
+	for _, n := range stats {
+		result.Engines += n
+	}
+	return result, nil
+}
+
+// fall back to VirusTotal for a hash Jotti doesn't know, no-op without -vt-key
+func checkVirusTotal(ctx context.Context, result *scanResult, hash string, w io.Writer) {
+	if vtKey == "" {
+		return
+	}
+	fmt.Fprintf(w, "Jotti: %s not found, checking VirusTotal\n", hash)
+	vt, err := vtLookup(ctx, hash)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Warn("VirusTotal lookup failed", "hash", hash, "error", err)
+		}
+		return
+	}
+	result.VirusTotal = vt
+	if !vt.Found {
+		fmt.Fprintf(w, "VirusTotal: %s not found\n", hash)
+		return
+	}
+	fmt.Fprintf(w, "VirusTotal: %d/%d engines detected\n%s\n", vt.Malicious, vt.Engines, vt.URL)
+}